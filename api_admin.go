@@ -9,7 +9,7 @@ package mpd
 //     id: Id of the output device. Use the 'outputs' command to find
 //         all valid Ids
 func (c *Client) DisableOutput(id int) (err error) {
-	_, err = c.request("disableoutput %d", id)
+	_, err = c.request("disableoutput", id)
 	return
 }
 
@@ -18,10 +18,86 @@ func (c *Client) DisableOutput(id int) (err error) {
 //     id: Id of the output device. Use the 'outputs' command to find
 //         all valid Ids.
 func (c *Client) EnableOutput(id int) (err error) {
-	_, err = c.request("enableoutput %d", id)
+	_, err = c.request("enableoutput", id)
 	return
 }
 
+// ToggleOutput turns an audio-output source on if it is off, and off if it
+// is on.
+//
+//     id: Id of the output device. Use the 'outputs' command to find
+//         all valid Ids.
+func (c *Client) ToggleOutput(id int) (err error) {
+	_, err = c.request("toggleoutput", id)
+	return
+}
+
+// OutputSet sets a runtime attribute of an output. The attributes an output
+// supports depend on its plugin; Outputs reports their current values.
+//
+//     id:    Id of the output device.
+//     name:  Name of the attribute, eg: "dop" or "allowed_formats".
+//     value: New value of the attribute.
+func (c *Client) OutputSet(id int, name, value string) (err error) {
+	if err = c.require("outputset", versionOutputSet); err != nil {
+		return
+	}
+
+	_, err = c.request("outputset", id, name, value)
+	return
+}
+
+// FindOutput returns the output with the given name. Output ids depend on
+// the order of the outputs in the configuration of the server, so names are
+// often the more stable way to refer to them. An unknown name results in an
+// error matching ErrNoExist.
+func (c *Client) FindOutput(name string) (o *Output, err error) {
+	var list []*Output
+
+	if list, err = c.Outputs(); err != nil {
+		return
+	}
+
+	for _, o = range list {
+		if o.Name == name {
+			return
+		}
+	}
+
+	return nil, &Error{Code: ErrNoExist, Command: "outputs", Message: "no such output: " + name}
+}
+
+// EnableOutputName turns the named audio-output source on.
+func (c *Client) EnableOutputName(name string) error {
+	return c.outputByName(name, c.EnableOutput)
+}
+
+// DisableOutputName turns the named audio-output source off.
+func (c *Client) DisableOutputName(name string) error {
+	return c.outputByName(name, c.DisableOutput)
+}
+
+// ToggleOutputName toggles the named audio-output source on or off.
+func (c *Client) ToggleOutputName(name string) error {
+	return c.outputByName(name, c.ToggleOutput)
+}
+
+// OutputSetName sets a runtime attribute of the named output.
+func (c *Client) OutputSetName(output, name, value string) error {
+	return c.outputByName(output, func(id int) error {
+		return c.OutputSet(id, name, value)
+	})
+}
+
+// outputByName looks up the id of the named output and passes it to fn.
+func (c *Client) outputByName(name string, fn func(id int) error) error {
+	o, err := c.FindOutput(name)
+	if err != nil {
+		return err
+	}
+	return fn(o.Id)
+}
+
 // Kill stops MPD from running in a safe way. Writes a state file if defined.
 func (c *Client) Kill() (err error) {
 	_, err = c.request("kill")
@@ -40,7 +116,22 @@ func (c *Client) Update(path string) (err error) {
 	if len(path) == 0 {
 		_, err = c.request("update")
 	} else {
-		_, err = c.request("update %q", path)
+		_, err = c.request("update", path)
 	}
 	return
 }
+
+// BinaryLimit sets the maximum size of the binary chunks the server sends in
+// a single response, as used by AlbumArt and ReadPicture. Larger chunks need
+// fewer round trips, but block the connection for longer.
+//
+//     size: Maximum chunk size in bytes. The server enforces a minimum of
+//           64 bytes.
+func (c *Client) BinaryLimit(size int) (err error) {
+	if err = c.require("binarylimit", versionBinaryLimit); err != nil {
+		return
+	}
+
+	_, err = c.request("binarylimit", size)
+	return
+}
@@ -5,8 +5,11 @@
 package mpd
 
 import (
+	"bytes"
 	"errors"
-	"fmt"
+	"io"
+	"iter"
+	"net/http"
 )
 
 // Find finds songs in the database with a case sensitive, exact match to `term`.
@@ -17,7 +20,7 @@ import (
 func (c *Client) Find(tag, term string) (list []*Song, err error) {
 	var a []Args
 
-	if a, err = c.requestList("find %q %q", tag, term); err != nil {
+	if a, err = c.requestList("find", tag, term); err != nil {
 		return
 	}
 
@@ -29,37 +32,100 @@ func (c *Client) Find(tag, term string) (list []*Song, err error) {
 	return
 }
 
-// List reports all metadata of type `tag1`.
+// FindSeq is like Find, but yields the songs one at a time as they arrive.
+func (c *Client) FindSeq(tag, term string) iter.Seq2[*Song, error] {
+	return readSongSeq(c.requestSeq("find", tag, term))
+}
+
+// FindFilter finds songs in the database matching the filter expression f.
+// Comparisons are case sensitive. The result can be sorted and limited with
+// the Sort, SortDesc and Window options. It requires protocol version 0.21.
+func (c *Client) FindFilter(f Filter, opts ...QueryOption) (list []*Song, err error) {
+	return c.filterSongs("find", f, opts...)
+}
+
+// FindFilterSeq is like FindFilter, but yields the songs one at a time as
+// they arrive.
+func (c *Client) FindFilterSeq(f Filter, opts ...QueryOption) iter.Seq2[*Song, error] {
+	return c.filterSongSeq("find", f, opts...)
+}
+
+// List reports the distinct values of tag1.
 //
 //     tag1: The type of metadata to list.
 //     tag2: Used together with `term`. This specifies to look for `tag2` in
 //           the list of `tag1` results.
 //     term: Used together with `tag2`. This specifies to look for matches
 //           of term in the list of `tag2` results.
-func (c *Client) List(tag1, tag2, term string) (list []*Song, err error) {
-	var a []Args
+func (c *Client) List(tag1, tag2, term string) (list []string, err error) {
+	var a Args
 
-	str := fmt.Sprintf("list %q", tag1)
+	args := []interface{}{tag1}
 
 	if tag2 > "" {
 		if len(term) == 0 {
 			return nil, errors.New("Missing parameter @term if parameter @tag2 has been supplied.")
 		}
 
-		str += fmt.Sprintf(" %q %q", tag1, tag2, term)
+		args = append(args, tag2, term)
 	}
 
-	if a, err = c.requestList(str); err != nil {
+	if a, err = c.request("list", args...); err != nil {
 		return
 	}
 
-	list = make([]*Song, 0, len(a))
+	return readValues(a, tag1), nil
+}
 
-	for _, m := range a {
-		list = append(list, readSong(m))
+// ListFilter reports the distinct values of tag among the songs matching
+// the filter expression f. A zero Filter matches all songs.
+func (c *Client) ListFilter(tag string, f Filter) (list []string, err error) {
+	var a Args
+
+	if a, err = c.listGrouped(tag, f, nil); err != nil {
+		return
 	}
 
-	return
+	return readValues(a, tag), nil
+}
+
+// ListGroups is like ListFilter, but groups the values by the given group
+// tags. The first group is the outermost one:
+//
+//     c.ListGroups("album", Filter{}, "albumartist", "date")
+//
+// yields a group for every album artist, holding a group for every date,
+// holding the albums of that artist released at that date.
+func (c *Client) ListGroups(tag string, f Filter, groups ...string) (list []*TagGroup, err error) {
+	var a Args
+
+	if a, err = c.listGrouped(tag, f, groups); err != nil {
+		return
+	}
+
+	return readTagGroups(a, tag), nil
+}
+
+// listGrouped sends a list command with an optional filter and groups.
+func (c *Client) listGrouped(tag string, f Filter, groups []string) (Args, error) {
+	args := []interface{}{tag}
+
+	if err := f.check(true); err != nil {
+		return nil, err
+	}
+
+	if len(f.expr) > 0 {
+		if err := c.require("list", f.need); err != nil {
+			return nil, err
+		}
+		args = append(args, f)
+	}
+
+	for _, g := range groups {
+		args = append(args, "group", g)
+	}
+
+	return c.request("list", args...)
 }
 
 // ListFiles reports all directories and filenames in path recursively.
@@ -69,23 +135,47 @@ func (c *Client) List(tag1, tag2, term string) (list []*Song, err error) {
 func (c *Client) ListFiles(path string) (list []string, err error) {
 	var a []Args
 
-	str := "listall"
+	var args []interface{}
 	if path != "" {
-		str += fmt.Sprintf(" %q", path)
+		args = append(args, path)
 	}
 
-	if a, err = c.requestList(str); err != nil {
+	if a, err = c.requestList("listall", args...); err != nil {
 		return
 	}
 
 	list = make([]string, 0, len(a))
 	for _, m := range a {
-		list = append(list, m["file"])
+		if file := m.S("file"); file != "" {
+			list = append(list, file)
+		}
 	}
 
 	return
 }
 
+// ListFilesSeq is like ListFiles, but yields the filenames one at a time as
+// they arrive.
+func (c *Client) ListFilesSeq(path string) iter.Seq2[string, error] {
+	var args []interface{}
+	if path != "" {
+		args = append(args, path)
+	}
+
+	return func(yield func(string, error) bool) {
+		for a, err := range c.requestSeq("listall", args...) {
+			if err != nil {
+				yield("", err)
+				return
+			}
+
+			if file := a.S("file"); file != "" && !yield(file, nil) {
+				return
+			}
+		}
+	}
+}
+
 // ListInfo reports all information in the database about all music files
 // in path recursively.
 //
@@ -93,13 +183,12 @@ func (c *Client) ListFiles(path string) (list []string, err error) {
 //           If omitted, we assume the music root as defined in mpd.conf.
 func (c *Client) ListInfo(path string) (list []*Song, err error) {
 	var a []Args
-	str := "listallinfo"
-
+	var args []interface{}
 	if len(path) > 0 {
-		str += fmt.Sprintf(" %q", path)
+		args = append(args, path)
 	}
 
-	if a, err = c.requestList(str); err != nil {
+	if a, err = c.requestList("listallinfo", args...); err != nil {
 		return
 	}
 
@@ -111,6 +200,22 @@ func (c *Client) ListInfo(path string) (list []*Song, err error) {
 	return
 }
 
+// ListInfoSeq is like ListInfo, but yields the songs one at a time as they
+// arrive, which keeps memory use bounded even for very large libraries.
+//
+// The connection is held until the whole response has been read, so the
+// client must not be used inside the loop. Breaking out of the loop early
+// reads and discards the rest of the response. The same holds for all other
+// methods whose names end in Seq.
+func (c *Client) ListInfoSeq(path string) iter.Seq2[*Song, error] {
+	var args []interface{}
+	if len(path) > 0 {
+		args = append(args, path)
+	}
+
+	return readSongSeq(c.requestSeq("listallinfo", args...))
+}
+
 // Ls reports a list of files/directories in `path`, from the database.
 //
 //     path: An optional directory path to act as the root of the list.
@@ -118,13 +223,12 @@ func (c *Client) ListInfo(path string) (list []*Song, err error) {
 func (c *Client) Ls(path string) (list []*Song, err error) {
 	var a []Args
 
-	str := "lsinfo"
-
+	var args []interface{}
 	if path != "" {
-		str += fmt.Sprintf(" %q", path)
+		args = append(args, path)
 	}
 
-	if a, err = c.requestList(str); err != nil {
+	if a, err = c.requestList("lsinfo", args...); err != nil {
 		return
 	}
 
@@ -136,6 +240,16 @@ func (c *Client) Ls(path string) (list []*Song, err error) {
 	return
 }
 
+// LsSeq is like Ls, but yields the entries one at a time as they arrive.
+func (c *Client) LsSeq(path string) iter.Seq2[*Song, error] {
+	var args []interface{}
+	if path != "" {
+		args = append(args, path)
+	}
+
+	return readSongSeq(c.requestSeq("lsinfo", args...))
+}
+
 // Search finds songs in the database with a case insensitive match to `term`.
 //
 //      tag: This is the type of metadata you wish to use to refine the search.
@@ -143,7 +257,7 @@ func (c *Client) Ls(path string) (list []*Song, err error) {
 func (c *Client) Search(tag, term string) (list []*Song, err error) {
 	var a []Args
 
-	if a, err = c.requestList("search %q %q", tag, term); err != nil {
+	if a, err = c.requestList("search", tag, term); err != nil {
 		return
 	}
 
@@ -155,19 +269,265 @@ func (c *Client) Search(tag, term string) (list []*Song, err error) {
 	return
 }
 
+// SearchSeq is like Search, but yields the songs one at a time as they
+// arrive.
+func (c *Client) SearchSeq(tag, term string) iter.Seq2[*Song, error] {
+	return readSongSeq(c.requestSeq("search", tag, term))
+}
+
+// SearchFilter finds songs in the database matching the filter expression
+// f. Comparisons ignore case. The result can be sorted and limited with the
+// Sort, SortDesc and Window options. It requires protocol version 0.21.
+func (c *Client) SearchFilter(f Filter, opts ...QueryOption) (list []*Song, err error) {
+	return c.filterSongs("search", f, opts...)
+}
+
+// SearchFilterSeq is like SearchFilter, but yields the songs one at a time
+// as they arrive.
+func (c *Client) SearchFilterSeq(f Filter, opts ...QueryOption) iter.Seq2[*Song, error] {
+	return c.filterSongSeq("search", f, opts...)
+}
+
+// filterSongs sends a command taking a filter expression and options, and
+// returns the songs it reports.
+func (c *Client) filterSongs(cmd string, f Filter, opts ...QueryOption) (list []*Song, err error) {
+	var a []Args
+
+	args, need, err := queryArgs(f, opts)
+	if err != nil {
+		return
+	}
+
+	if err = c.require(cmd, need); err != nil {
+		return
+	}
+
+	if a, err = c.requestList(cmd, args...); err != nil {
+		return
+	}
+
+	return readSongs(a), nil
+}
+
+// filterSongSeq is like filterSongs, but yields the songs as they arrive.
+func (c *Client) filterSongSeq(cmd string, f Filter, opts ...QueryOption) iter.Seq2[*Song, error] {
+	args, need, err := queryArgs(f, opts)
+	if err == nil {
+		err = c.require(cmd, need)
+	}
+
+	if err != nil {
+		return func(yield func(*Song, error) bool) { yield(nil, err) }
+	}
+
+	return readSongSeq(c.requestSeq(cmd, args...))
+}
+
 // Count reports the number of songs and their total playtime in the
 // database matching `term`.
 //
 //      tag: This is the type of metadata you wish to use to refine the search.
 //     term: This is the value that is being searched for in tag.
 func (c *Client) Count(tag, term string) (songs, playtime int, err error) {
-	var a []Args
+	var a Args
+
+	if a, err = c.request("count", tag, term); err != nil {
+		return
+	}
+
+	songs = a.I("songs")
+	playtime = a.I("playtime")
+	return
+}
+
+// CountFilter reports the number of songs and their total playtime in the
+// database matching the filter expression f. It requires protocol version
+// 0.21.
+func (c *Client) CountFilter(f Filter) (songs, playtime int, err error) {
+	var a Args
+
+	if err = f.check(false); err != nil {
+		return
+	}
 
-	if a, err = c.requestList("count %q %q", tag, term); err != nil || len(a) == 0 {
+	if err = c.require("count", f.need); err != nil {
 		return
 	}
 
-	songs = a[0].I("songs")
-	playtime = a[0].I("playtime")
+	if a, err = c.request("count", f); err != nil {
+		return
+	}
+
+	songs = a.I("songs")
+	playtime = a.I("playtime")
 	return
 }
+
+// FindAdd adds the songs in the database matching the filter expression f
+// to the queue. Comparisons are case sensitive. The songs are appended,
+// unless a Position option is given. Sort and Window options select and
+// order the songs added. It requires protocol version 0.21.
+func (c *Client) FindAdd(f Filter, opts ...QueryOption) error {
+	return c.filterAdd("findadd", f, opts)
+}
+
+// SearchAdd is like FindAdd, but comparisons ignore case.
+func (c *Client) SearchAdd(f Filter, opts ...QueryOption) error {
+	return c.filterAdd("searchadd", f, opts)
+}
+
+// SearchAddPlaylist adds the songs in the database matching the filter
+// expression f to the stored playlist called name, which is created if it
+// does not exist. Comparisons ignore case. The options are the same as for
+// FindAdd; a Position option requires protocol version 0.24.
+func (c *Client) SearchAddPlaylist(name string, f Filter, opts ...QueryOption) (err error) {
+	args, need, err := queryArgs(f, opts)
+	if err != nil {
+		return
+	}
+
+	for _, o := range opts {
+		if o.name == "position" {
+			need = versionPlaylistAddPos
+		}
+	}
+
+	if err = c.require("searchaddpl", need); err != nil {
+		return
+	}
+
+	_, err = c.request("searchaddpl", append([]interface{}{name}, args...)...)
+	return
+}
+
+// filterAdd sends findadd or searchadd.
+func (c *Client) filterAdd(cmd string, f Filter, opts []QueryOption) (err error) {
+	args, need, err := queryArgs(f, opts)
+	if err != nil {
+		return
+	}
+
+	if err = c.require(cmd, need); err != nil {
+		return
+	}
+
+	_, err = c.request(cmd, args...)
+	return
+}
+
+// CountGroup reports the number of songs and their total playtime for
+// every value of the group tag, among the songs matching the filter
+// expression f. A zero Filter matches all songs. It requires protocol
+// version 0.21.
+func (c *Client) CountGroup(group string, f Filter) (list []GroupCount, err error) {
+	var a Args
+
+	if err = f.check(true); err != nil {
+		return
+	}
+
+	if err = c.require("count", versionFilter); err != nil {
+		return
+	}
+
+	var args []interface{}
+	if len(f.expr) > 0 {
+		if err = c.require("count", f.need); err != nil {
+			return
+		}
+		args = append(args, f)
+	}
+
+	if a, err = c.request("count", append(args, "group", group)...); err != nil {
+		return
+	}
+
+	return readGroupCounts(a, group), nil
+}
+
+// AlbumArt returns the cover art file found in the directory of the song at
+// uri, along with its MIME type. The server does not report the type of
+// such files, so it is derived from their content.
+//
+//     uri: Path of a song, relative to the music directory.
+func (c *Client) AlbumArt(uri string) (data []byte, mime string, err error) {
+	var buf bytes.Buffer
+
+	if mime, err = c.AlbumArtTo(&buf, uri); err != nil {
+		return
+	}
+
+	data = buf.Bytes()
+	return
+}
+
+// AlbumArtTo is like AlbumArt, but writes the file to w chunk by chunk as
+// it arrives.
+func (c *Client) AlbumArtTo(w io.Writer, uri string) (mime string, err error) {
+	return c.readBinary(w, "albumart", uri)
+}
+
+// ReadPicture returns the picture embedded in the song at uri, along with
+// its MIME type. If the song has no embedded picture, data is empty.
+//
+//     uri: Path of a song, relative to the music directory.
+func (c *Client) ReadPicture(uri string) (data []byte, mime string, err error) {
+	var buf bytes.Buffer
+
+	if mime, err = c.ReadPictureTo(&buf, uri); err != nil {
+		return
+	}
+
+	data = buf.Bytes()
+	return
+}
+
+// ReadPictureTo is like ReadPicture, but writes the picture to w chunk by
+// chunk as it arrives.
+func (c *Client) ReadPictureTo(w io.Writer, uri string) (mime string, err error) {
+	return c.readBinary(w, "readpicture", uri)
+}
+
+// readBinary requests a file with the given command, one chunk at a time,
+// until all of it has been written to w. The size of the chunks can be
+// tuned with BinaryLimit. Other commands may run between the chunks.
+func (c *Client) readBinary(w io.Writer, cmd, uri string) (mime string, err error) {
+	var a Args
+	var chunk []byte
+	var offset int
+
+	need := versionAlbumArt
+	if cmd == "readpicture" {
+		need = versionReadPicture
+	}
+
+	if err = c.require(cmd, need); err != nil {
+		return
+	}
+
+	for {
+		if a, chunk, err = c.requestBinary(cmd, uri, offset); err != nil {
+			return
+		}
+
+		// An empty response means there is nothing to read.
+		if !a.Has("size") {
+			return
+		}
+
+		if offset == 0 {
+			if mime = a.S("type"); len(mime) == 0 {
+				mime = http.DetectContentType(chunk)
+			}
+		}
+
+		if _, err = w.Write(chunk); err != nil {
+			return
+		}
+
+		offset += len(chunk)
+		if len(chunk) == 0 || offset >= a.I("size") {
+			return
+		}
+	}
+}
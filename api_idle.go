@@ -1,3 +1,7 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
 package mpd
 
 type SubSystem uint8
@@ -13,78 +17,100 @@ const (
 	StickerSystem
 	SubscriptionSystem
 	MessageSystem
+	PartitionSystem
+	NeighborSystem
+	OptionsSystem
+	MountSystem
 )
 
-func readSubSystem(a Args) SubSystem {
-	var s SubSystem
-
-	switch a.S("changed") {
-	case "database":
-		s = DatabaseSystem
-	case "update":
-		s = UpdateSystem
-	case "stored_playlist":
-		s = StoredPlaylistSystem
-	case "playlist":
-		s = PlaylistSystem
-	case "player":
-		s = PlayerSystem
-	case "mixer":
-		s = MixerSystem
-	case "output":
-		s = OutputSystem
-	case "sticker":
-		s = StickerSystem
-	case "subscription":
-		s = SubscriptionSystem
-	case "message":
-		s = MessageSystem
+var subSystemNames = [...]string{
+	DatabaseSystem:       "database",
+	UpdateSystem:         "update",
+	StoredPlaylistSystem: "stored_playlist",
+	PlaylistSystem:       "playlist",
+	PlayerSystem:         "player",
+	MixerSystem:          "mixer",
+	OutputSystem:         "output",
+	StickerSystem:        "sticker",
+	SubscriptionSystem:   "subscription",
+	MessageSystem:        "message",
+	PartitionSystem:      "partition",
+	NeighborSystem:       "neighbor",
+	OptionsSystem:        "options",
+	MountSystem:          "mount",
+}
+
+// String returns the name the protocol uses for the subsystem.
+func (s SubSystem) String() string {
+	if int(s) < len(subSystemNames) {
+		return subSystemNames[s]
+	}
+	return "unknown"
+}
+
+// parseSubSystem returns the subsystem with the given protocol name.
+func parseSubSystem(name string) (SubSystem, bool) {
+	for i, v := range subSystemNames {
+		if v == name {
+			return SubSystem(i), true
+		}
 	}
-	return s
+	return 0, false
 }
 
+// Idle waits until something changes in any subsystem and returns the
+// subsystem that changed. If several subsystems changed at once, only the
+// first is reported; use IdleSubSystems to receive all of them.
 func (c *Client) Idle() (s SubSystem, err error) {
-	var a Args
+	var list []SubSystem
 
-	if a, err = c.request("idle"); err != nil {
+	if list, err = c.IdleSubSystems(); err != nil {
 		return
 	}
 
-	s = readSubSystem(a)
+	s = list[0]
 	return
 }
 
+// IdleSubSystem waits until something changes in the given subsystem.
+//
+//     subsystem: The subsystem to watch.
 func (c *Client) IdleSubSystem(subsystem SubSystem) (changed bool, err error) {
-	var a Args
-	var s string
-
-	switch subsystem {
-	case DatabaseSystem:
-		s = "database"
-	case UpdateSystem:
-		s = "update"
-	case StoredPlaylistSystem:
-		s = "stored_playlist"
-	case PlaylistSystem:
-		s = "playlist"
-	case PlayerSystem:
-		s = "player"
-	case MixerSystem:
-		s = "mixer"
-	case OutputSystem:
-		s = "output"
-	case StickerSystem:
-		s = "sticker"
-	case SubscriptionSystem:
-		s = "subscription"
-	case MessageSystem:
-		s = "message"
-	}
+	var list []SubSystem
 
-	if a, err = c.request("idle %s", s); err != nil {
+	if list, err = c.IdleSubSystems(subsystem); err != nil {
 		return
 	}
 
-	changed = a != nil
+	changed = len(list) > 0
+	return
+}
+
+// IdleSubSystems waits until something changes in any of the given
+// subsystems and returns every subsystem that changed. Changes in subsystems
+// unknown to this package are not reported; it keeps waiting instead.
+//
+//     subsystems: The subsystems to watch. If none are given, all
+//                 subsystems are watched.
+func (c *Client) IdleSubSystems(subsystems ...SubSystem) (list []SubSystem, err error) {
+	var names []string
+
+	args := make([]string, len(subsystems))
+	for i, s := range subsystems {
+		args[i] = s.String()
+	}
+
+	for len(list) == 0 {
+		if names, err = c.requestIdle(args); err != nil {
+			return
+		}
+
+		for _, name := range names {
+			if s, ok := parseSubSystem(name); ok {
+				list = append(list, s)
+			}
+		}
+	}
+
 	return
 }
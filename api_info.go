@@ -33,7 +33,7 @@ func (c *Client) Stats() (s *Stats, err error) {
 func (c *Client) Outputs() (list []*Output, err error) {
 	var a []Args
 
-	if a, err = c.requestList("outputs"); err != nil {
+	if a, err = c.requestSplit([]string{"outputid"}, "outputs"); err != nil {
 		return
 	}
 
@@ -48,68 +48,44 @@ func (c *Client) Outputs() (list []*Output, err error) {
 
 // Commands reports which commands the current user has access to.
 func (c *Client) Commands() (v []string, err error) {
-	var a []Args
-	if a, err = c.requestList("commands"); err != nil {
+	var a Args
+	if a, err = c.request("commands"); err != nil {
 		return
 	}
 
-	var k string
-	for _, m := range a {
-		for _, k = range m {
-			v = append(v, k)
-		}
-	}
-
+	v = a.Values("command")
 	return
 }
 
 // NotCommands reports which commands the current user has *no* access to.
 func (c *Client) NotCommands() (v []string, err error) {
-	var a []Args
-	if a, err = c.requestList("notcommands"); err != nil {
+	var a Args
+	if a, err = c.request("notcommands"); err != nil {
 		return
 	}
 
-	var k string
-	for _, m := range a {
-		for _, k = range m {
-			v = append(v, k)
-		}
-	}
-
+	v = a.Values("command")
 	return
 }
 
 // TagTypes reports a list of available song metadata fields.
 func (c *Client) TagTypes() (v []string, err error) {
-	var a []Args
-	if a, err = c.requestList("tagtypes"); err != nil {
+	var a Args
+	if a, err = c.request("tagtypes"); err != nil {
 		return
 	}
 
-	var k string
-	for _, m := range a {
-		for _, k = range m {
-			v = append(v, k)
-		}
-	}
-
+	v = a.Values("tagtype")
 	return
 }
 
 // UrlHandlers reports a list of available URL handlers.
 func (c *Client) UrlHandlers() (v []string, err error) {
-	var a []Args
-	if a, err = c.requestList("urlhandlers"); err != nil {
+	var a Args
+	if a, err = c.request("urlhandlers"); err != nil {
 		return
 	}
 
-	var k string
-	for _, m := range a {
-		for _, k = range m {
-			v = append(v, k)
-		}
-	}
-
+	v = a.Values("handler")
 	return
 }
@@ -0,0 +1,67 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+// Message is a message sent by a client to a channel.
+type Message struct {
+	Channel string
+	Text    string
+}
+
+// Subscribe subscribes the connection to a channel. Messages sent to it are
+// kept until they are read with ReadMessages. The channel is created if it
+// does not exist.
+//
+//     channel: Name of the channel. It may consist of letters, digits and
+//              the characters '_', '-', '.' and ':'.
+func (c *Client) Subscribe(channel string) (err error) {
+	_, err = c.request("subscribe", channel)
+	return
+}
+
+// Unsubscribe unsubscribes the connection from a channel.
+func (c *Client) Unsubscribe(channel string) (err error) {
+	_, err = c.request("unsubscribe", channel)
+	return
+}
+
+// Channels reports the channels which have at least one subscriber.
+func (c *Client) Channels() (list []string, err error) {
+	var a Args
+
+	if a, err = c.request("channels"); err != nil {
+		return
+	}
+
+	return a.Values("channel"), nil
+}
+
+// SendMessage sends text to all clients subscribed to channel. It fails with
+// ErrNoExist if there are none.
+func (c *Client) SendMessage(channel, text string) (err error) {
+	_, err = c.request("sendmessage", channel, text)
+	return
+}
+
+// ReadMessages returns the messages received on the subscribed channels
+// since the last call, in the order they were sent. The server announces
+// new messages to idle clients as a change in MessageSystem.
+func (c *Client) ReadMessages() (list []Message, err error) {
+	var a Args
+
+	if a, err = c.request("readmessages"); err != nil {
+		return
+	}
+
+	for _, f := range a {
+		switch {
+		case f.Key == "channel":
+			list = append(list, Message{Channel: f.Value})
+		case f.Key == "message" && len(list) > 0:
+			list[len(list)-1].Text = f.Value
+		}
+	}
+	return
+}
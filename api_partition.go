@@ -0,0 +1,101 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+// Partition returns a client which shares the connection of c, but whose
+// commands act on the named partition: its queue, player state and outputs.
+// The partition is selected before each command whenever the connection is
+// in a different one, so bound clients for several partitions can be used
+// side by side. The binding survives Reconnect.
+//
+// The partition must exist; see NewPartition. Partitions require protocol
+// version 0.21; commands sent through the returned client fail with an
+// *UnsupportedError on older servers.
+func (c *Client) Partition(name string) *Client {
+	c2 := new(Client)
+	*c2 = *c
+	c2.partition = name
+	return c2
+}
+
+// SwitchPartition moves the connection to the named partition. Clients which
+// are not bound to a partition through Partition act on it from then on,
+// including after Reconnect. It requires protocol version 0.21.
+func (c *Client) SwitchPartition(name string) (err error) {
+	if err = c.require("partition", versionPartition); err != nil {
+		return
+	}
+
+	command := NewCommand("partition", name)
+	if err = command.Err(); err != nil {
+		return
+	}
+
+	return c.exchange(func() (err error) {
+		if err = c.send(command.String()); err != nil {
+			return
+		}
+
+		if _, err = c.receive(); err != nil {
+			return
+		}
+
+		c.active = name
+		c.selected = name
+		return
+	})
+}
+
+// ListPartitions reports the names of all partitions. It requires protocol
+// version 0.21.
+func (c *Client) ListPartitions() (list []string, err error) {
+	var a Args
+
+	if err = c.require("listpartitions", versionPartition); err != nil {
+		return
+	}
+
+	if a, err = c.request("listpartitions"); err != nil {
+		return
+	}
+
+	return a.Values("partition"), nil
+}
+
+// NewPartition creates a new partition with the given name. It starts out
+// with an empty queue and no outputs; see MoveOutput. It requires protocol
+// version 0.21.
+func (c *Client) NewPartition(name string) (err error) {
+	if err = c.require("newpartition", versionPartition); err != nil {
+		return
+	}
+
+	_, err = c.request("newpartition", name)
+	return
+}
+
+// DelPartition deletes the named partition. The default partition and
+// partitions which still have clients in them can not be deleted. Its
+// outputs are moved back to the default partition. It requires protocol
+// version 0.22.
+func (c *Client) DelPartition(name string) (err error) {
+	if err = c.require("delpartition", versionDelPartition); err != nil {
+		return
+	}
+
+	_, err = c.request("delpartition", name)
+	return
+}
+
+// MoveOutput moves the named output to the partition the client acts on.
+// It requires protocol version 0.21.
+func (c *Client) MoveOutput(name string) (err error) {
+	if err = c.require("moveoutput", versionPartition); err != nil {
+		return
+	}
+
+	_, err = c.request("moveoutput", name)
+	return
+}
@@ -11,8 +11,8 @@ func (c *Client) Toggle() (err error) {
 		return
 	}
 
-	if arg["state"] == "play" {
-		_, err = c.request("pause 1")
+	if arg.S("state") == "play" {
+		_, err = c.request("pause", 1)
 	} else {
 		_, err = c.request("play")
 	}
@@ -23,7 +23,7 @@ func (c *Client) Toggle() (err error) {
 //
 //     time: Crossfade time in seconds.
 func (c *Client) Crossfade(time int) (err error) {
-	_, err = c.request("crossfade %d", time)
+	_, err = c.request("crossfade", time)
 	return
 }
 
@@ -35,7 +35,7 @@ func (c *Client) Pause(toggle bool) (err error) {
 	if toggle {
 		v = 1
 	}
-	_, err = c.request("pause %d", v)
+	_, err = c.request("pause", v)
 	return
 }
 
@@ -43,7 +43,7 @@ func (c *Client) Pause(toggle bool) (err error) {
 // 
 //     pos: Position of song to play.
 func (c *Client) Play(pos int) (err error) {
-	_, err = c.request("play %d", pos)
+	_, err = c.request("play", pos)
 	return
 }
 
@@ -51,7 +51,7 @@ func (c *Client) Play(pos int) (err error) {
 //
 //     id: Id of the song to play.
 func (c *Client) PlayId(id int) (err error) {
-	_, err = c.request("playid %d", id)
+	_, err = c.request("playid", id)
 	return
 }
 
@@ -75,7 +75,7 @@ func (c *Client) Random(toggle bool) (err error) {
 	if toggle {
 		v = 1
 	}
-	_, err = c.request("random %d", v)
+	_, err = c.request("random", v)
 	return
 }
 
@@ -87,7 +87,20 @@ func (c *Client) Repeat(toggle bool) (err error) {
 	if toggle {
 		v = 1
 	}
-	_, err = c.request("repeat %d", v)
+	_, err = c.request("repeat", v)
+	return
+}
+
+// Single sets the single mode. SingleOneshot requires protocol version
+// 0.21.
+func (c *Client) Single(mode SingleMode) (err error) {
+	if mode == SingleOneshot {
+		if err = c.require("single oneshot", versionSingleOneshot); err != nil {
+			return
+		}
+	}
+
+	_, err = c.request("single", mode)
 	return
 }
 
@@ -96,7 +109,7 @@ func (c *Client) Repeat(toggle bool) (err error) {
 //      pos: Position of song.
 //     time: Time in seconds to jump to.
 func (c *Client) Seek(pos, time int) (err error) {
-	_, err = c.request("seek %d %d", pos, time)
+	_, err = c.request("seek", pos, time)
 	return
 }
 
@@ -105,7 +118,7 @@ func (c *Client) Seek(pos, time int) (err error) {
 //       id: Id of song.
 //     time: Time in seconds to jump to.
 func (c *Client) SeekId(id, time int) (err error) {
-	_, err = c.request("seekid %d %d", id, time)
+	_, err = c.request("seekid", id, time)
 	return
 }
 
@@ -134,7 +147,7 @@ func (c *Client) Volume(vol byte, relative bool) (err error) {
 		vol = 100
 	}
 
-	_, err = c.request("setvol %d", vol)
+	_, err = c.request("setvol", vol)
 	return
 }
 
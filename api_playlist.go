@@ -4,7 +4,7 @@
 
 package mpd
 
-import "fmt"
+import "iter"
 
 // Add adds a single file from the database to the playlist. This command
 // increments the playlist version by 1 for each song added to the playlist.
@@ -12,7 +12,7 @@ import "fmt"
 //     path: A single directory or file. If path is a directory, all files in it
 //           are added recursively.
 func (c *Client) Add(path string) (err error) {
-	_, err = c.request("add %q", path)
+	_, err = c.request("add", path)
 	return
 }
 
@@ -25,9 +25,9 @@ func (c *Client) Add(path string) (err error) {
 //           Supply -1 to insert at the end of the list.
 func (c *Client) AddId(path string, pos int) (err error) {
 	if pos > -1 {
-		_, err = c.request("addid %q %d", path, pos)
+		_, err = c.request("addid", path, pos)
 	} else {
-		_, err = c.request("addid %q", path)
+		_, err = c.request("addid", path)
 	}
 	return
 }
@@ -38,9 +38,15 @@ func (c *Client) Clear() (err error) {
 	return
 }
 
-// Current reports the metadata of the currently playing song.
-func (c *Client) Current() (Args, error) {
-	return c.request("currentsong")
+// Current reports the metadata of the current song. It returns nil if there
+// is none.
+func (c *Client) Current() (s *Song, err error) {
+	var a Args
+	if a, err = c.request("currentsong"); err != nil {
+		return
+	}
+
+	return readCurrentSong(a), nil
 }
 
 // Delete deletes the specified song from the playlist. Increments the playlist
@@ -48,7 +54,7 @@ func (c *Client) Current() (Args, error) {
 //
 //     pos: Position of the song in the playlist.
 func (c *Client) Delete(pos int) (err error) {
-	_, err = c.request("delete %d", pos)
+	_, err = c.request("delete", pos)
 	return
 }
 
@@ -57,7 +63,7 @@ func (c *Client) Delete(pos int) (err error) {
 //
 //     id: Id of the song to delete.
 func (c *Client) DeleteId(id int) (err error) {
-	_, err = c.request("deleteid %d", id)
+	_, err = c.request("deleteid", id)
 	return
 }
 
@@ -67,7 +73,7 @@ func (c *Client) DeleteId(id int) (err error) {
 //     name: Name of the playlist file *without* the path and file extension.
 //           eg: `/path/to/all.m3u` -> `all`.
 func (c *Client) Load(name string) (err error) {
-	_, err = c.request("load %q", name)
+	_, err = c.request("load", name)
 	return
 }
 
@@ -78,7 +84,7 @@ func (c *Client) Load(name string) (err error) {
 //     oldname: Current name of the playlist.
 //     newname: New name of the playlist.
 func (c *Client) Rename(oldname, newname string) (err error) {
-	_, err = c.request("rename %q %q", oldname, newname)
+	_, err = c.request("rename", oldname, newname)
 	return
 }
 
@@ -87,7 +93,7 @@ func (c *Client) Rename(oldname, newname string) (err error) {
 //     src: Source position.
 //     dst: Target position.
 func (c *Client) Move(src, dst int) (err error) {
-	_, err = c.request("move %d %d", src, dst)
+	_, err = c.request("move", src, dst)
 	return
 }
 
@@ -96,7 +102,7 @@ func (c *Client) Move(src, dst int) (err error) {
 //     src: Id of source song.
 //     dst: Target position.
 func (c *Client) MoveId(src, dst int) (err error) {
-	_, err = c.request("moveid %d %d", src, dst)
+	_, err = c.request("moveid", src, dst)
 	return
 }
 
@@ -106,15 +112,13 @@ func (c *Client) MoveId(src, dst int) (err error) {
 //          information for. Specify -1 to report for all songs.
 func (c *Client) PlaylistInfo(pos int) (list []*Song, err error) {
 	var a []Args
-	var str string
+	var args []interface{}
 
-	if pos == -1 {
-		str = "playlistinfo"
-	} else {
-		str = fmt.Sprintf("playlistinfo %d", pos)
+	if pos != -1 {
+		args = append(args, pos)
 	}
 
-	if a, err = c.requestList(str); err != nil {
+	if a, err = c.requestList("playlistinfo", args...); err != nil {
 		return
 	}
 
@@ -126,13 +130,24 @@ func (c *Client) PlaylistInfo(pos int) (list []*Song, err error) {
 	return
 }
 
+// PlaylistInfoSeq is like PlaylistInfo, but yields the songs one at a time
+// as they arrive. See ListInfoSeq for how the loop may use the client.
+func (c *Client) PlaylistInfoSeq(pos int) iter.Seq2[*Song, error] {
+	var args []interface{}
+	if pos != -1 {
+		args = append(args, pos)
+	}
+
+	return readSongSeq(c.requestSeq("playlistinfo", args...))
+}
+
 // PlaylistChanges reports changed songs in the playlist since version.
 //
 //     version: The playlist version to display changed songs for.
 func (c *Client) PlaylistChanges(version int) (list []*Song, err error) {
 	var a []Args
 
-	if a, err = c.requestList("plchanges %d", version); err != nil {
+	if a, err = c.requestList("plchanges", version); err != nil {
 		return
 	}
 
@@ -149,7 +164,7 @@ func (c *Client) PlaylistChanges(version int) (list []*Song, err error) {
 //     name: Name of the playlist file *without* the path and file extension.
 //           eg: `/path/to/all.m3u` -> `all`.
 func (c *Client) PlaylistRm(name string) (err error) {
-	_, err = c.request("rm %q", name)
+	_, err = c.request("rm", name)
 	return
 }
 
@@ -158,7 +173,7 @@ func (c *Client) PlaylistRm(name string) (err error) {
 //     name: Name of the playlist file *without* the path and file extension.
 //           eg: `/path/to/all.m3u` -> `all`.
 func (c *Client) Save(name string) (err error) {
-	_, err = c.request("save %q", name)
+	_, err = c.request("save", name)
 	return
 }
 
@@ -174,7 +189,7 @@ func (c *Client) Shuffle() (err error) {
 //     src: Source position.
 //     dst: Target position.
 func (c *Client) Swap(src, dst int) (err error) {
-	_, err = c.request("swap %d %d", src, dst)
+	_, err = c.request("swap", src, dst)
 	return
 }
 
@@ -184,7 +199,7 @@ func (c *Client) Swap(src, dst int) (err error) {
 //     src: Source id.
 //     dst: Target id.
 func (c *Client) SwapId(src, dst int) (err error) {
-	_, err = c.request("swapid %d %d", src, dst)
+	_, err = c.request("swapid", src, dst)
 	return
 }
 
@@ -193,18 +208,12 @@ func (c *Client) SwapId(src, dst int) (err error) {
 //     name: Name of the playlist file *without* the path and file extension.
 //           eg: `/path/to/all.m3u` -> `all`.
 func (c *Client) ListPlaylistFiles(name string) (v []string, err error) {
-	var a []Args
-	if a, err = c.requestList("listplaylist %q", name); err != nil {
+	var a Args
+	if a, err = c.request("listplaylist", name); err != nil {
 		return
 	}
 
-	var k string
-	for _, m := range a {
-		for _, k = range m {
-			v = append(v, k)
-		}
-	}
-
+	v = a.Values("file")
 	return
 }
 
@@ -212,7 +221,7 @@ func (c *Client) ListPlaylistFiles(name string) (v []string, err error) {
 //
 func (c *Client) ListPlaylists() (p []*Playlist, err error) {
 	var a []Args
-	if a, err = c.requestList("listplaylists"); err != nil {
+	if a, err = c.requestSplit([]string{"playlist"}, "listplaylists"); err != nil {
 		return
 	}
 
@@ -230,7 +239,7 @@ func (c *Client) ListPlaylists() (p []*Playlist, err error) {
 func (c *Client) ListPlaylistSongs(name string) (list []*Song, err error) {
 	var a []Args
 
-	if a, err = c.requestList("listplaylistinfo %q", name); err != nil {
+	if a, err = c.requestList("listplaylistinfo", name); err != nil {
 		return
 	}
 
@@ -248,7 +257,7 @@ func (c *Client) ListPlaylistSongs(name string) (list []*Song, err error) {
 //           eg: `/path/to/all.m3u` -> `all`.
 //     path: Path of file(s) to add to the given playlist.
 func (c *Client) PlaylistAdd(name, path string) (err error) {
-	_, err = c.request("playlistadd %q %q", name, path)
+	_, err = c.request("playlistadd", name, path)
 	return
 }
 
@@ -257,7 +266,7 @@ func (c *Client) PlaylistAdd(name, path string) (err error) {
 //     name: Name of the playlist file *without* the path and file extension.
 //           eg: `/path/to/all.m3u` -> `all`.
 func (c *Client) PlaylistClear(name string) (err error) {
-	_, err = c.request("playlistclear %q", name)
+	_, err = c.request("playlistclear", name)
 	return
 }
 
@@ -267,7 +276,7 @@ func (c *Client) PlaylistClear(name string) (err error) {
 //            eg: `/path/to/all.m3u` -> `all`.
 //        id: ID of song to delete.
 func (c *Client) PlaylistDelete(name string, id int) (err error) {
-	_, err = c.request("playlistdelete %q %d", name, id)
+	_, err = c.request("playlistdelete", name, id)
 	return
 }
 
@@ -279,7 +288,7 @@ func (c *Client) PlaylistDelete(name string, id int) (err error) {
 //       id: ID of song to move.
 //      pos: Position to move song to.
 func (c *Client) PlaylistMove(name string, id, pos int) (err error) {
-	_, err = c.request("playlistmove %q %d %d", name, id, pos)
+	_, err = c.request("playlistmove", name, id, pos)
 	return
 }
 
@@ -290,7 +299,7 @@ func (c *Client) PlaylistMove(name string, id, pos int) (err error) {
 func (c *Client) PlaylistSearch(tag, term string) (list []*Song, err error) {
 	var a []Args
 
-	if a, err = c.requestList("playlistsearch %q %q", tag, term); err != nil {
+	if a, err = c.requestList("playlistsearch", tag, term); err != nil {
 		return
 	}
 
@@ -301,3 +310,29 @@ func (c *Client) PlaylistSearch(tag, term string) (list []*Song, err error) {
 
 	return
 }
+
+// PlaylistSearchFilter finds songs in the current playlist matching the
+// filter expression f. Comparisons ignore case. It requires protocol
+// version 0.21; sorting and windowing the result requires 0.24.
+func (c *Client) PlaylistSearchFilter(f Filter, opts ...QueryOption) (list []*Song, err error) {
+	return c.playlistFilter("playlistsearch", f, opts)
+}
+
+// PlaylistFindFilter finds songs in the current playlist matching the
+// filter expression f. Comparisons are case sensitive. It requires protocol
+// version 0.21; sorting and windowing the result requires 0.24.
+func (c *Client) PlaylistFindFilter(f Filter, opts ...QueryOption) (list []*Song, err error) {
+	return c.playlistFilter("playlistfind", f, opts)
+}
+
+// playlistFilter sends playlistfind or playlistsearch. Their options were
+// added later than those of find and search.
+func (c *Client) playlistFilter(cmd string, f Filter, opts []QueryOption) (list []*Song, err error) {
+	if len(opts) > 0 {
+		if err = c.require(cmd, versionPlaylistOptions); err != nil {
+			return
+		}
+	}
+
+	return c.filterSongs(cmd, f, opts...)
+}
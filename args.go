@@ -6,7 +6,22 @@ package mpd
 
 import "strconv"
 
-type Args map[string]string
+// Field is a single 'key: value' line of a response.
+type Field struct {
+	Key   string
+	Value string
+}
+
+// Args holds the fields of a response, or of a single entry in a list, in
+// the order the server sent them. Keys may occur more than once; a song
+// with several artists has an 'Artist' field for each of them.
+//
+// The accessors read the first field with the given key. Values returns
+// all of them.
+type Args []Field
+
+// Keys which start a new entry in the responses of most listing commands.
+var entryKeys = []string{"file", "directory", "playlist"}
 
 func (a Args) B(k string) bool  { return a.I(k) == 1 }
 func (a Args) U8(k string) byte { return byte(a.I(k)) }
@@ -46,10 +61,65 @@ func (a Args) S(k string) string {
 	return ""
 }
 
-func (a Args) read(key string) string {
-	v, ok := a[key]
-	if !ok {
-		return ""
+// Get returns the value of the first field with the given key, and whether
+// there is such a field.
+func (a Args) Get(k string) (string, bool) {
+	for _, f := range a {
+		if f.Key == k {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Has reports whether there is a field with the given key.
+func (a Args) Has(k string) bool {
+	_, ok := a.Get(k)
+	return ok
+}
+
+// Values returns the values of all fields with the given key, in order.
+func (a Args) Values(k string) (v []string) {
+	for _, f := range a {
+		if f.Key == k {
+			v = append(v, f.Value)
+		}
+	}
+	return
+}
+
+// Split divides a list response into its entries. A new entry starts at
+// every field whose key is one of keys:
+//
+//     a.Split("file", "directory", "playlist")
+func (a Args) Split(keys ...string) (list []Args) {
+	var start int
+
+	for i, f := range a {
+		if i > start && isKey(f.Key, keys) {
+			list = append(list, a[start:i:i])
+			start = i
+		}
+	}
+
+	if start < len(a) {
+		list = append(list, a[start:len(a):len(a)])
 	}
+
+	return
+}
+
+func (a Args) read(key string) string {
+	v, _ := a.Get(key)
 	return v
 }
+
+// isKey reports whether k is one of keys.
+func isKey(k string, keys []string) bool {
+	for _, v := range keys {
+		if v == k {
+			return true
+		}
+	}
+	return false
+}
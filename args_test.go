@@ -0,0 +1,47 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestArgsSplit(t *testing.T) {
+	a := Args{
+		{"directory", "music"},
+		{"Last-Modified", "2019-05-03T10:00:00Z"},
+		{"file", "music/a.flac"},
+		{"Artist", "Alice"},
+		{"Artist", "Bob"},
+		{"Title", "Duet"},
+		{"file", "music/b.flac"},
+		{"Artist", "Carol"},
+		{"playlist", "favourites"},
+	}
+
+	list := a.Split(entryKeys...)
+	if len(list) != 4 {
+		t.Fatalf("Split: got %d entries, want 4: %v", len(list), list)
+	}
+
+	if got := list[1].Values("Artist"); !reflect.DeepEqual(got, []string{"Alice", "Bob"}) {
+		t.Errorf("Values(Artist) = %v; want [Alice Bob]", got)
+	}
+
+	if got := list[1].S("Title"); got != "Duet" {
+		t.Errorf("S(Title) = %q; want Duet", got)
+	}
+
+	if got := list[3].S("playlist"); got != "favourites" {
+		t.Errorf("S(playlist) = %q; want favourites", got)
+	}
+
+	// Appending to an entry must not clobber the next one.
+	_ = append(list[1], Field{"Genre", "Pop"})
+	if list[2][0].Key != "file" {
+		t.Errorf("entries share their backing array")
+	}
+}
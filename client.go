@@ -6,79 +6,388 @@ package mpd
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"iter"
 	"net"
-	"regexp"
+	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
-// Used to test whether we are compatible with the MPD server.
-var SupportedVersion = [3]int{0, 15, 0}
+// Used to test whether we are compatible with the MPD server. The Dial
+// functions reject servers with an older protocol version; use a Dialer to
+// choose a different minimum.
+var SupportedVersion = Version{0, 15, 0}
 
+// Client represents a connection to an MPD server. It is safe for concurrent
+// use by multiple goroutines; each command is sent and its response read as
+// a single exchange.
+//
+// ServerVersion and ProtocolVersion describe the server the client is
+// connected to. Clients derived from it share them, and Reconnect updates
+// them.
 type Client struct {
-	conn            net.Conn
-	writer          *bufio.Writer
-	reader          *bufio.Reader
-	ProtocolVersion string
+	*session
+	ctx       context.Context
+	partition string // Partition bound to by Partition, if any.
 }
 
+// session holds the connection state shared by a Client and all the clients
+// derived from it through WithContext and Partition.
+//
+// Every request/response exchange holds mu for its full duration, which makes
+// a Client safe for concurrent use. A pending idle does not hold mu while it
+// waits; other exchanges interrupt it and it resumes once they are done.
+type session struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	writer *bufio.Writer
+	reader *bufio.Reader
+	idle   *idleState
+
+	// The partition the connection is currently in, or empty if unknown,
+	// and the one chosen with SwitchPartition for clients not bound to one.
+	// New connections start out in the default partition.
+	active   string
+	selected string
+
+	// Used by Reconnect. Dial is nil for clients made by NewClient.
+	dial       func(ctx context.Context) (net.Conn, error)
+	password   string
+	minVersion Version
+
+	// Set by the handshake of every new connection. Once the client has
+	// been returned, they are only read and written with mu held.
+	protocolVersion string  // Greeting sent by the server, eg: "MPD 0.23.5".
+	version         Version // Protocol version of the server.
+}
+
+// idleState describes an idle command which is waiting for its response.
+type idleState struct {
+	done   chan struct{} // Closed once the response has been read.
+	noidle func()        // Asks the server to end the idle; safe to call twice.
+
+	partition  string   // Partition the idle was sent in.
+	subsystems []string // Subsystems waited for; empty for all of them.
+	changed    []string // Set before done is closed.
+}
+
+// aLongTimeAgo is a deadline in the past. Setting it on a connection unblocks
+// any pending reads and writes.
+var aLongTimeAgo = time.Unix(1, 0)
+
+// DefaultHost and DefaultPort are used to find the MPD server when neither an
+// explicit address nor the MPD_HOST and MPD_PORT environment variables have
+// been supplied.
+const (
+	DefaultHost = "localhost"
+	DefaultPort = "6600"
+)
+
 // Dial opens a new connection to the specified MPD server and optionally
 // logs in with the given password.
 func Dial(address, password string) (c *Client, err error) {
-	c = new(Client)
+	return DialNetwork("tcp", address, password)
+}
+
+// DialNetwork opens a new connection to the MPD server at the given address
+// and optionally logs in with the given password.
+//
+//     network: One of the networks supported by net.Dial. Usually "tcp" or
+//              "unix".
+//     address: The host:port pair for tcp connections, or the path of the
+//              socket for unix connections. Abstract sockets are denoted
+//              by a leading '@'.
+func DialNetwork(network, address, password string) (c *Client, err error) {
+	return DialContext(context.Background(), network, address, password)
+}
+
+// DialContext is like DialNetwork, but uses ctx to bound the time spent
+// connecting, completing the handshake and logging in. Once the connection
+// has been established, ctx no longer affects the returned client.
+func DialContext(ctx context.Context, network, address, password string) (c *Client, err error) {
+	d := Dialer{MinVersion: SupportedVersion}
+	return d.DialContext(ctx, network, address, password)
+}
+
+// Dialer holds options for connecting to an MPD server.
+//
+// The options of a Dialer only apply to the clients made by its DialContext
+// method. The other ways of connecting, which are Dial, DialNetwork,
+// DialContext, DialHost, DialEnv, NewClient, NewWatcher and NewSubscriber,
+// always use SupportedVersion as the minimum version.
+type Dialer struct {
+	// MinVersion is the oldest protocol version accepted. Connecting to an
+	// older server fails with an *UnsupportedError. The zero value accepts
+	// any server.
+	MinVersion Version
+}
+
+// DialContext opens a new connection to the MPD server at the given address
+// and optionally logs in with the given password. See DialNetwork and
+// DialContext for the meaning of the arguments.
+func (d *Dialer) DialContext(ctx context.Context, network, address, password string) (c *Client, err error) {
+	var nd net.Dialer
+	var conn net.Conn
 
-	if c.conn, err = net.Dial("tcp", address); err != nil {
+	if conn, err = nd.DialContext(ctx, network, address); err != nil {
 		return
 	}
 
+	if c, err = newClient(ctx, conn, password, d.MinVersion); err != nil {
+		return nil, err
+	}
+
+	c.dial = func(ctx context.Context) (net.Conn, error) {
+		return nd.DialContext(ctx, network, address)
+	}
+
+	c.ctx = nil
+	return
+}
+
+// DialHost opens a new connection to the MPD server described by host. See
+// ParseHost for the accepted formats.
+func DialHost(host string) (c *Client, err error) {
+	network, address, password := ParseHost(host, "")
+	return DialNetwork(network, address, password)
+}
+
+// DialEnv opens a new connection to the MPD server described by the MPD_HOST
+// and MPD_PORT environment variables, the same way the reference mpc client
+// does. Missing values default to DefaultHost and DefaultPort.
+func DialEnv() (c *Client, err error) {
+	network, address, password := ParseHost(os.Getenv("MPD_HOST"), os.Getenv("MPD_PORT"))
+	return DialNetwork(network, address, password)
+}
+
+// ParseHost splits an MPD host description into values suitable for
+// DialNetwork. The host takes the same form as the MPD_HOST variable:
+//
+//     [password@]host[:port]
+//     [password@]/path/to/socket
+//     [password@]@abstract-socket
+//
+// An empty host is replaced by DefaultHost. An empty port is replaced by
+// DefaultPort. The port is only used when host does not carry one itself.
+func ParseHost(host, port string) (network, address, password string) {
+	if len(port) == 0 {
+		port = DefaultPort
+	}
+
+	// A leading '@' denotes an abstract socket, not an empty password.
+	if len(host) > 0 && host[0] != '@' {
+		if pos := strings.Index(host, "@"); pos > -1 {
+			password, host = host[:pos], host[pos+1:]
+		}
+	}
+
+	if len(host) == 0 {
+		host = DefaultHost
+	}
+
+	if host[0] == '/' || host[0] == '@' {
+		return "unix", host, password
+	}
+
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return "tcp", host, password
+	}
+
+	return "tcp", net.JoinHostPort(strings.Trim(host, "[]"), port), password
+}
+
+// NewClient returns a client for an already established connection to an
+// MPD server, such as one end of a net.Pipe. It completes the handshake and
+// optionally logs in with the given password.
+func NewClient(conn net.Conn, password string) (c *Client, err error) {
+	if c, err = newClient(context.Background(), conn, password, SupportedVersion); err != nil {
+		return nil, err
+	}
+
+	c.ctx = nil
+	return
+}
+
+// newClient completes the handshake on a freshly opened connection and logs
+// in with the given password, if any. Servers older than min are rejected.
+func newClient(ctx context.Context, conn net.Conn, password string, min Version) (c *Client, err error) {
+	c = new(Client)
+	c.session = new(session)
+	c.ctx = ctx
+	c.conn = conn
 	c.reader = bufio.NewReader(c.conn)
 	c.writer = bufio.NewWriter(c.conn)
+	c.active = "default"
+	c.password = password
+	c.minVersion = min
+
+	if err = c.exchange(c.login); err != nil {
+		c.Close()
+		return nil, err
+	}
+
+	return
+}
+
+// login completes the handshake on a freshly opened connection and logs in.
+func (c *Client) login() (err error) {
+	if err = c.handshake(c.minVersion); err != nil {
+		return
+	}
+
+	if len(c.password) > 0 {
+		command := NewCommand("password", c.password)
+		if err = command.Err(); err != nil {
+			return
+		}
+
+		if err = c.send(command.String()); err != nil {
+			return
+		}
+
+		if _, err = c.receive(); err != nil {
+			return
+		}
+	}
+
+	return
+}
 
+// Reconnect closes the connection and opens a new one to the same server,
+// logging in with the same password. Clients derived from c through
+// WithContext and Partition share the new connection. The partitions chosen
+// with Partition and SwitchPartition are selected again before the next
+// command.
+//
+// Only clients made by the Dial functions can reconnect.
+func (c *Client) Reconnect() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ctx := c.Context()
+	if c.dial == nil {
+		return errors.New("Client can not reconnect; it was not made by a Dial function.")
+	}
+
+	if c.conn != nil {
+		c.interruptIdle(ctx)
+		c.send("close")
+		c.abort()
+	}
+
+	var conn net.Conn
+	if conn, err = c.dial(ctx); err != nil {
+		return
+	}
+
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	c.writer = bufio.NewWriter(conn)
+	c.active = "default"
+
+	unwatch := watch(ctx, conn)
+	err = c.login()
+	unwatch()
+
+	if err != nil {
+		c.abort()
+		if e := contextErr(ctx); e != nil {
+			err = e
+		}
+	}
+
+	return
+}
+
+// handshake reads the greeting sent by the server when a connection is
+// opened and checks that its protocol version is at least min.
+func (c *Client) handshake(min Version) (err error) {
 	// Complete handshake. Server should send 'OK MPD 0.15.0'. This is the
 	// protocol version, not the version of the MPD daemon itself. We can use it
 	// to test if our program is compatible with the api exposed by the daemon.
 	var data string
 	if data, err = c.reader.ReadString('\n'); err != nil {
-		c.Close()
-		return nil, err
+		return
 	}
 
 	if data = strings.TrimSpace(data); len(data) == 0 {
-		c.Close()
-		return nil, errors.New("No valid handshake received.")
+		return errors.New("No valid handshake received.")
 	}
 
-	if data[0:3] == "ACK" {
-		c.Close()
-		return nil, errors.New(fmt.Sprintf("Handshake error: %s", data[4:]))
+	if strings.HasPrefix(data, "ACK") {
+		return parseAck(data)
 	}
 
-	c.ProtocolVersion = data[3:]
-	if !isSupportedVersion(c.ProtocolVersion) {
-		c.Close()
-		return nil, errors.New(fmt.Sprintf(
-			"Invalid protocol version. This library requires at least 'MPD %d.%d.%d'. Server sent '%s'.",
-			SupportedVersion[0], SupportedVersion[1], SupportedVersion[2],
-			c.ProtocolVersion,
-		))
+	if !strings.HasPrefix(data, "OK MPD ") {
+		return errors.New(fmt.Sprintf("Invalid handshake received: '%s'.", data))
 	}
 
-	if len(password) > 0 {
-		_, err = c.request("password \"%s\"", password)
+	c.protocolVersion = data[3:]
+	if c.version, err = ParseVersion(c.protocolVersion); err != nil {
+		return
+	}
+
+	if !c.version.AtLeast(min) {
+		return &UnsupportedError{Need: min, Have: c.version}
 	}
 
 	return
 }
 
+// WithContext returns a client which shares the connection of c, but applies
+// the deadline and cancellation of ctx to every command it sends.
+//
+// When ctx ends during a pending Idle call, the idle is cancelled with
+// 'noidle' and the connection remains usable. When ctx ends while any other
+// response is only partially read, the state of the connection is unknown
+// and it is closed.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	if ctx == nil {
+		panic("mpd: nil context")
+	}
+
+	c2 := new(Client)
+	*c2 = *c
+	c2.ctx = ctx
+	return c2
+}
+
+// Context returns the context used by this client. It defaults to
+// context.Background.
+func (c *Client) Context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	return context.Background()
+}
+
 // Close the open connection.
 // The error returned is an os.Error to satisfy io.Closer;
+//
+// Clients derived through WithContext share the connection, so closing any
+// one of them closes all of them.
 func (c *Client) Close() (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.conn != nil {
+		c.interruptIdle(c.Context())
 		c.send("close")
+		err = c.abort()
+	}
 
+	return
+}
+
+// abort closes the connection without notifying the server.
+func (c *Client) abort() (err error) {
+	if c.conn != nil {
 		c.reader = nil
 		c.writer = nil
 
@@ -89,111 +398,628 @@ func (c *Client) Close() (err error) {
 	return
 }
 
+// interruptIdle ends a pending idle, if any, and waits until its response has
+// been read. The caller must hold c.mu. If ctx ends first, the connection is
+// closed.
+func (c *Client) interruptIdle(ctx context.Context) {
+	idle := c.idle
+	if idle == nil {
+		return
+	}
+
+	idle.noidle()
+
+	select {
+	case <-idle.done:
+	case <-ctx.Done():
+		// Closing the connection unblocks the pending idle. Its caller
+		// owns the reader until it is done, so only then can we clean up.
+		c.conn.Close()
+		<-idle.done
+		c.abort()
+	}
+
+	c.idle = nil
+}
+
+// exchange runs fn, which performs a single request/response exchange with
+// the server, under the deadline and cancellation of the client's context.
+// No other exchange can take place until fn returns.
+func (c *Client) exchange(fn func() error) (err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ctx := c.Context()
+	if err = ctx.Err(); err != nil {
+		return
+	}
+
+	c.interruptIdle(ctx)
+
+	if c.conn == nil {
+		return errors.New("Connection is closed.")
+	}
+
+	unwatch := watch(ctx, c.conn)
+	if err = c.selectPartition(); err == nil {
+		err = fn()
+	}
+	unwatch()
+
+	if isTimeout(err) {
+		// The exchange was interrupted halfway through. Whatever is left of
+		// the response is still on its way, so the connection is unusable.
+		c.abort()
+
+		if e := contextErr(ctx); e != nil {
+			err = e
+		}
+	}
+
+	return
+}
+
+// targetPartition returns the name of the partition the client's commands
+// act on. c.mu must be held.
+func (c *Client) targetPartition() string {
+	name := c.partition
+	if len(name) == 0 {
+		name = c.selected
+	}
+
+	if len(name) == 0 {
+		name = "default"
+	}
+
+	return name
+}
+
+// selectPartition switches the connection to the partition the client acts
+// on, unless it is already there. The caller must hold c.mu.
+func (c *Client) selectPartition() (err error) {
+	name := c.targetPartition()
+	if name == c.active {
+		return
+	}
+
+	// The caller holds c.mu, so c.version is read directly.
+	if !c.version.AtLeast(versionPartition) {
+		return &UnsupportedError{Command: "partition", Need: versionPartition, Have: c.version}
+	}
+
+	command := NewCommand("partition", name)
+	if err = command.Err(); err != nil {
+		return
+	}
+
+	c.active = ""
+	if err = c.send(command.String()); err != nil {
+		return
+	}
+
+	if _, err = c.receive(); err != nil {
+		return
+	}
+
+	c.active = name
+	return
+}
+
+// watch applies the deadline and cancellation of ctx to conn. The returned
+// function stops doing so and clears the deadline again.
+func watch(ctx context.Context, conn net.Conn) (unwatch func()) {
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	var mu sync.Mutex
+	var unwatched bool
+
+	stop := context.AfterFunc(ctx, func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !unwatched {
+			conn.SetDeadline(aLongTimeAgo)
+		}
+	})
+
+	return func() {
+		stop()
+
+		mu.Lock()
+		unwatched = true
+		mu.Unlock()
+
+		conn.SetDeadline(time.Time{})
+	}
+}
+
+// contextErr is like ctx.Err, but also reports an expired deadline when the
+// connection noticed it before the context did.
+func contextErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+
+	return nil
+}
+
+func isTimeout(err error) bool {
+	ne, ok := err.(net.Error)
+	return ok && ne.Timeout()
+}
+
 func (c *Client) parseError(line string) error {
 	if strings.HasPrefix(line, "ACK ") {
-		// sig: [errcode@token] {command} message
-		//  ex: [2@0] {enableoutput} wrong number of arguments for "enableoutput"
-		pos := strings.Index(line, "}")
-		return errors.New(strings.TrimSpace(line[pos+1:]))
+		return parseAck(line)
 	}
 	return errors.New(line)
 }
 
+// Command sends an arbitrary command and returns its response. The
+// arguments are encoded as described for the Command type. This allows the
+// use of commands which this package does not wrap.
+func (c *Client) Command(cmd string, arg ...interface{}) (Args, error) {
+	return c.request(cmd, arg...)
+}
+
+// CommandSplit is like Command, but splits the response into entries. A
+// new entry starts at every field whose key is one of keys:
+//
+//     list, err := c.CommandSplit([]string{"neighbor"}, "listneighbors")
+//
+// The entries can be decoded with Unmarshal or UnmarshalList.
+func (c *Client) CommandSplit(keys []string, cmd string, arg ...interface{}) ([]Args, error) {
+	return c.requestSplit(keys, cmd, arg...)
+}
+
 func (c *Client) request(cmd string, arg ...interface{}) (args Args, err error) {
-	if err = c.send(fmt.Sprintf(cmd, arg...)); err != nil {
+	command := NewCommand(cmd, arg...)
+	if err = command.Err(); err != nil {
 		return
 	}
-	return c.receive()
+
+	err = c.exchange(func() (err error) {
+		if err = c.send(command.String()); err != nil {
+			return
+		}
+		args, err = c.receive()
+		return
+	})
+	return
 }
 
+// requestList sends a command whose response is a list of songs,
+// directories or playlists and returns its entries.
 func (c *Client) requestList(cmd string, arg ...interface{}) (args []Args, err error) {
-	if err = c.send(fmt.Sprintf(cmd, arg...)); err != nil {
+	return c.requestSplit(entryKeys, cmd, arg...)
+}
+
+// requestSplit sends a command and splits its response into entries. A new
+// entry starts at every field whose key is one of keys.
+func (c *Client) requestSplit(keys []string, cmd string, arg ...interface{}) (args []Args, err error) {
+	command := NewCommand(cmd, arg...)
+	if err = command.Err(); err != nil {
 		return
 	}
-	return c.receiveList()
+
+	err = c.exchange(func() (err error) {
+		if err = c.send(command.String()); err != nil {
+			return
+		}
+		args, err = c.receiveList(keys)
+		return
+	})
+	return
 }
 
-func (c *Client) receive() (data Args, err error) {
-	if c.reader == nil {
-		return nil, errors.New("Stream reader is closed.")
+// requestBinary sends a command whose response carries a binary payload,
+// such as 'albumart'. It returns the fields of the response and the payload.
+func (c *Client) requestBinary(cmd string, arg ...interface{}) (args Args, data []byte, err error) {
+	command := NewCommand(cmd, arg...)
+	if err = command.Err(); err != nil {
+		return
 	}
 
-	data = make(Args)
-	var line string
-	var pos int
+	err = c.exchange(func() (err error) {
+		if err = c.send(command.String()); err != nil {
+			return
+		}
+		args, data, err = c.receiveBinary()
+		return
+	})
+	return
+}
+
+// requestIdle sends an idle command and waits for its response. It returns
+// the names of the changed subsystems among those given, or among all of
+// them if none are given.
+//
+// The wait does not block other users of the client. Their exchanges end the
+// idle with 'noidle' and it is sent again once they are done. If the client's
+// context ends first, the idle is cancelled the same way, so the connection
+// remains in a known state.
+//
+// Idles do not interrupt each other. One which waits for the same partition
+// and subsystems as the pending idle shares its response. One which waits for
+// more replaces it with an idle for both sets, which the first then shares.
+// Idles for different partitions take turns.
+func (c *Client) requestIdle(subsystems []string) (changed []string, err error) {
+	ctx := c.Context()
+	watched := subsystems
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
 	for {
-		if line, err = c.reader.ReadString('\n'); err != nil {
-			return nil, err
+		if err = contextErr(ctx); err != nil {
+			return
 		}
 
-		if line = strings.TrimSpace(line); len(line) > 0 {
-			if line == "OK" {
-				break
+		if idle := c.idle; idle != nil {
+			same := idle.partition == c.targetPartition()
+
+			if !same || covers(idle.subsystems, watched) {
+				c.mu.Unlock()
+
+				select {
+				case <-idle.done:
+				case <-ctx.Done():
+				}
+
+				c.mu.Lock()
+
+				if same {
+					if changed = filterChanged(idle.changed, subsystems); len(changed) > 0 {
+						return
+					}
+				}
+				continue
+			}
+
+			watched = unionSubSystems(idle.subsystems, watched)
+		}
+
+		c.interruptIdle(ctx)
+
+		if c.conn == nil {
+			return nil, errors.New("Connection is closed.")
+		}
+
+		conn := c.conn
+		unwatch := watch(ctx, conn)
+
+		if err = c.selectPartition(); err == nil {
+			args := make([]interface{}, len(watched))
+			for i, name := range watched {
+				args[i] = name
+			}
+			err = c.send(NewCommand("idle", args...).String())
+		}
+
+		if err != nil {
+			unwatch()
+			if isTimeout(err) {
+				c.abort()
 			}
+			return
+		}
+
+		idle := &idleState{
+			done:       make(chan struct{}),
+			partition:  c.active,
+			subsystems: watched,
+		}
+		idle.noidle = sync.OnceFunc(func() {
+			io.WriteString(conn, "noidle\n")
+		})
+		c.idle = idle
+
+		// Let others use the connection while we wait.
+		c.mu.Unlock()
 
-			if strings.HasPrefix(line, "ACK ") {
-				return nil, c.parseError(line)
+		changed, err = c.receiveChanged()
+
+		if isTimeout(err) && contextErr(ctx) != nil {
+			// Lift the deadline set by the context, so we can tell the
+			// server to stop waiting and read the final response.
+			unwatch()
+			idle.noidle()
+
+			if changed, err = c.receiveChanged(); err == nil {
+				err = contextErr(ctx)
 			}
+		}
+
+		unwatch()
+
+		if !isTimeout(err) {
+			idle.changed = changed
+		}
+		close(idle.done)
+
+		c.mu.Lock()
+
+		if c.idle == idle {
+			c.idle = nil
+		}
+
+		if isTimeout(err) {
+			c.abort()
+			return
+		}
+
+		if err != nil {
+			return
+		}
+
+		if changed = filterChanged(changed, subsystems); len(changed) > 0 {
+			return
+		}
+
+		// An empty response means another exchange interrupted us before
+		// anything we wait for changed. Go back to waiting.
+	}
+}
+
+// covers reports whether an idle for the subsystems in have also waits for
+// those in want. An empty set stands for all subsystems.
+func covers(have, want []string) bool {
+	if len(have) == 0 {
+		return true
+	}
+
+	if len(want) == 0 {
+		return false
+	}
 
-			if pos = strings.Index(line, ":"); line[0:pos] == "error" {
-				return nil, c.parseError(line)
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
 			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// unionSubSystems returns the subsystems in either a or b. An empty set
+// stands for all subsystems.
+func unionSubSystems(a, b []string) []string {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+
+	list := append([]string(nil), a...)
+	for _, name := range b {
+		if !covers(list, []string{name}) {
+			list = append(list, name)
+		}
+	}
+
+	return list
+}
 
-			data[line[0:pos]] = strings.TrimSpace(line[pos+1:])
+// filterChanged returns the names in changed which are among subsystems, or
+// all of them if subsystems is empty.
+func filterChanged(changed, subsystems []string) (list []string) {
+	if len(subsystems) == 0 {
+		return changed
+	}
+
+	for _, name := range changed {
+		if covers(subsystems, []string{name}) {
+			list = append(list, name)
 		}
 	}
+
 	return
 }
 
-func (c *Client) receiveList() (data []Args, err error) {
-	var line string
-	var pos int
+// receiveChanged reads the response to an idle command and returns the
+// values of all its 'changed' lines.
+func (c *Client) receiveChanged() (changed []string, err error) {
+	var key, value, term string
 
-	if c.reader == nil {
-		return nil, errors.New("Stream reader is closed.")
+	for {
+		if key, value, term, err = c.readPair(); err != nil {
+			return nil, err
+		}
+
+		if len(term) > 0 {
+			break
+		}
+
+		if key == "changed" {
+			changed = append(changed, value)
+		}
 	}
+	return
+}
 
-	a := make(Args)
+func (c *Client) receive() (data Args, err error) {
+	var key, value, term string
 
 	for {
-		if line, err = c.reader.ReadString('\n'); err != nil {
+		if key, value, term, err = c.readPair(); err != nil {
 			return nil, err
 		}
 
-		if line = strings.TrimSpace(line); len(line) > 0 {
-			if line == "OK" {
-				if len(a) > 0 {
-					data = append(data, a)
-				}
-				break
-			}
+		if len(term) > 0 {
+			break
+		}
+
+		data = append(data, Field{key, value})
+	}
+	return
+}
+
+// receiveBinary reads a response which may carry a binary payload. The
+// payload is announced by a 'binary: N' line, followed by N raw bytes and a
+// newline.
+func (c *Client) receiveBinary() (data Args, payload []byte, err error) {
+	var key, value, term string
+	var size int
+
+	for {
+		if key, value, term, err = c.readPair(); err != nil {
+			return nil, nil, err
+		}
+
+		if len(term) > 0 {
+			break
+		}
+
+		data = append(data, Field{key, value})
+
+		if key != "binary" {
+			continue
+		}
+
+		if size, err = strconv.Atoi(value); err != nil || size < 0 {
+			return nil, nil, errors.New("Invalid binary size: " + value)
+		}
+
+		payload = make([]byte, size+1)
+		if _, err = io.ReadFull(c.reader, payload); err != nil {
+			return nil, nil, err
+		}
+
+		if payload[size] != '\n' {
+			return nil, nil, errors.New("Binary payload is not terminated by a newline.")
+		}
+
+		payload = payload[:size]
+	}
+	return
+}
+
+func (c *Client) receiveList(keys []string) (data []Args, err error) {
+	err = c.receiveEach(keys, func(a Args) {
+		data = append(data, a)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// receiveEach reads a response holding a list of entries and calls fn for
+// each entry as soon as it is complete. A new entry starts at every field
+// whose key is one of keys.
+func (c *Client) receiveEach(keys []string, fn func(a Args)) (err error) {
+	var key, value, term string
+	var a Args
+
+	for {
+		if key, value, term, err = c.readPair(); err != nil {
+			return
+		}
 
-			if strings.HasPrefix(line, "ACK ") {
-				return nil, c.parseError(line)
+		if len(term) > 0 {
+			if len(a) > 0 {
+				fn(a)
 			}
+			break
+		}
 
-			if pos = strings.Index(line, ":"); line[0:pos] == "error" {
-				return nil, c.parseError(line)
+		if len(a) > 0 && isKey(key, keys) {
+			fn(a)
+			a = nil
+		}
+
+		a = append(a, Field{key, value})
+	}
+	return
+}
+
+// requestSeq sends a command whose response is a list of songs, directories
+// or playlists and yields its entries one at a time, as they arrive. The
+// connection is held until the response has been read completely, so the
+// client must not be used inside the loop. If the loop ends early, the rest
+// of the response is read and discarded.
+func (c *Client) requestSeq(cmd string, arg ...interface{}) iter.Seq2[Args, error] {
+	return func(yield func(Args, error) bool) {
+		stopped := false
+
+		command := NewCommand(cmd, arg...)
+		if err := command.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err := c.exchange(func() (err error) {
+			if err = c.send(command.String()); err != nil {
+				return
 			}
 
-			// Lists of entries are not delimited by a special token. We need
-			// to tell them apart by checking for keys in the map which already
-			// exist. If so, we are dealing with a new entry.
-			if _, ok := a[line[0:pos]]; ok {
-				if len(a) > 0 {
-					data = append(data, a)
+			return c.receiveEach(entryKeys, func(a Args) {
+				if !stopped && !yield(a, nil) {
+					stopped = true
 				}
-				a = make(Args)
-			}
+			})
+		})
 
-			a[line[0:pos]] = strings.TrimSpace(line[pos+1:])
+		if err != nil && !stopped {
+			yield(nil, err)
 		}
 	}
+}
+
+// readPair reads the next key/value pair of a response. When the line
+// terminating the response has been read instead, term holds it; this is
+// "OK", or "list_OK" for the responses to commands in a command list.
+func (c *Client) readPair() (key, value, term string, err error) {
+	var line string
+	var pos int
+
+	if c.reader == nil {
+		return "", "", "", errors.New("Stream reader is closed.")
+	}
+
+	for {
+		if line, err = c.reader.ReadString('\n'); err != nil {
+			return
+		}
+
+		if line = strings.TrimSpace(line); len(line) > 0 {
+			break
+		}
+	}
+
+	if line == "OK" || line == "list_OK" {
+		return "", "", line, nil
+	}
+
+	if strings.HasPrefix(line, "ACK ") {
+		return "", "", "", c.parseError(line)
+	}
+
+	if pos = strings.Index(line, ":"); pos == -1 {
+		return line, "", "", nil
+	}
+
+	key = line[0:pos]
+	value = strings.TrimSpace(line[pos+1:])
 	return
 }
 
-func (c *Client) send(msg string, args ...interface{}) (err error) {
+// send writes a single line to the server. The line must already be encoded;
+// use NewCommand to build it.
+func (c *Client) send(line string) (err error) {
 	const max_retries = 3
 	var tries, num int
 
@@ -201,15 +1027,14 @@ func (c *Client) send(msg string, args ...interface{}) (err error) {
 		return errors.New("Stream writer is closed.")
 	}
 
-	msg = fmt.Sprintf(msg, args...)
-	msg += "\n"
+	line += "\n"
 
 	for tries = 0; tries < max_retries; tries++ {
-		if num, err = c.writer.WriteString(msg); num < len(msg) {
+		if num, err = c.writer.WriteString(line); num < len(line) {
 			time.Sleep(300000000) // 0.3 seconds between retries
 			continue
 		}
-		c.writer.Flush()
+		err = c.writer.Flush()
 		break
 	}
 
@@ -239,44 +1064,3 @@ func (c *Client) LocalAddr() net.Addr { return c.conn.LocalAddr() }
 
 // RemoteAddr returns the remote network address.
 func (c *Client) RemoteAddr() net.Addr { return c.conn.RemoteAddr() }
-
-func isSupportedVersion(ver string) bool {
-	var reg_version *regexp.Regexp
-	var err error
-
-	if reg_version, err = regexp.Compile(`^MPD ([0-9]+).([0-9]+).([0-9]+)$`); err != nil {
-		return false
-	}
-
-	matches := reg_version.FindStringSubmatch(ver)
-	if len(matches) == 0 {
-		return false
-	}
-
-	version := [3]int{0, 0, 0}
-	version[0], _ = strconv.Atoi(matches[1])
-	version[1], _ = strconv.Atoi(matches[2])
-	version[2], _ = strconv.Atoi(matches[3])
-
-	if version[0] < SupportedVersion[0] {
-		return false
-	}
-
-	if version[0] > SupportedVersion[0] {
-		return true
-	}
-
-	if version[1] < SupportedVersion[1] {
-		return false
-	}
-
-	if version[1] > SupportedVersion[1] {
-		return true
-	}
-
-	if version[2] < SupportedVersion[2] {
-		return false
-	}
-
-	return true
-}
@@ -0,0 +1,495 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseHost(t *testing.T) {
+	tests := []struct {
+		host, port                 string
+		network, address, password string
+	}{
+		{"", "", "tcp", "localhost:6600", ""},
+		{"", "6601", "tcp", "localhost:6601", ""},
+		{"example.org", "", "tcp", "example.org:6600", ""},
+		{"example.org:7000", "6601", "tcp", "example.org:7000", ""},
+		{"secret@example.org", "", "tcp", "example.org:6600", "secret"},
+		{"secret@example.org:7000", "", "tcp", "example.org:7000", "secret"},
+		{"secret@", "", "tcp", "localhost:6600", "secret"},
+		{"::1", "", "tcp", "[::1]:6600", ""},
+		{"[::1]:7000", "", "tcp", "[::1]:7000", ""},
+		{"/run/mpd/socket", "", "unix", "/run/mpd/socket", ""},
+		{"secret@/run/mpd/socket", "", "unix", "/run/mpd/socket", "secret"},
+		{"@mpd", "", "unix", "@mpd", ""},
+		{"secret@@mpd", "", "unix", "@mpd", "secret"},
+	}
+
+	for _, tt := range tests {
+		network, address, password := ParseHost(tt.host, tt.port)
+		if network != tt.network || address != tt.address || password != tt.password {
+			t.Errorf("ParseHost(%q, %q) = %q, %q, %q; want %q, %q, %q",
+				tt.host, tt.port, network, address, password,
+				tt.network, tt.address, tt.password)
+		}
+	}
+}
+
+// pipeClient returns a client connected to server through an in-memory pipe.
+// The server greets the client before handing over the connection.
+func pipeClient(t *testing.T, server func(r *bufio.Reader, w net.Conn)) *Client {
+	cc, sc := net.Pipe()
+
+	go func() {
+		defer sc.Close()
+		io.WriteString(sc, "OK MPD 0.23.5\n")
+		server(bufio.NewReader(sc), sc)
+	}()
+
+	c, err := newClient(context.Background(), cc, "", Version{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c.ctx = nil
+	return c
+}
+
+func TestHandshake(t *testing.T) {
+	tests := []struct {
+		greeting string
+		ok       bool
+	}{
+		{"OK MPD 0.23.5\n", true},
+		{"OK\n", false},
+		{"AC\n", false},
+		{"ACK [4@0] {} too many connections\n", false},
+		{"HELLO\n", false},
+	}
+
+	for _, tt := range tests {
+		cc, sc := net.Pipe()
+		go func() {
+			defer sc.Close()
+			io.WriteString(sc, tt.greeting)
+			io.Copy(io.Discard, sc)
+		}()
+
+		c, err := NewClient(cc, "")
+		if (err == nil) != tt.ok {
+			t.Errorf("greeting %q: got error %v", tt.greeting, err)
+		}
+
+		if c != nil {
+			c.Close()
+		}
+		cc.Close()
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		r.ReadString('\n')
+		select {} // Never answer.
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := c.WithContext(ctx).Status(); err != context.DeadlineExceeded {
+		t.Fatalf("Status: got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if c.conn != nil {
+		t.Fatal("connection should be closed after an interrupted response")
+	}
+}
+
+func TestContextIdleCancel(t *testing.T) {
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch strings.TrimSpace(line) {
+			case "idle":
+				// Wait for noidle.
+			case "noidle", "ping":
+				io.WriteString(w, "OK\n")
+			}
+		}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	if _, err := c.WithContext(ctx).Idle(); err != context.Canceled {
+		t.Fatalf("Idle: got error %v, want %v", err, context.Canceled)
+	}
+
+	if _, err := c.request("ping"); err != nil {
+		t.Fatalf("connection unusable after cancelled idle: %v", err)
+	}
+}
+
+func TestConcurrentExchanges(t *testing.T) {
+	notify := make(chan struct{})
+
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		lines := make(chan string)
+		go func() {
+			defer close(lines)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				lines <- strings.TrimSpace(line)
+			}
+		}()
+
+		idling, pending := false, false
+		for {
+			select {
+			case <-notify:
+				pending = true
+			case line, ok := <-lines:
+				if !ok {
+					return
+				}
+
+				switch line {
+				case "idle":
+					idling = true
+				case "noidle":
+					if idling {
+						io.WriteString(w, "OK\n")
+						idling = false
+					}
+				case "status":
+					io.WriteString(w, "volume: 42\nstate: play\nOK\n")
+				case "playlistinfo":
+					io.WriteString(w, "file: a.mp3\nPos: 0\nfile: b.mp3\nPos: 1\nOK\n")
+				}
+			}
+
+			if idling && pending {
+				io.WriteString(w, "changed: player\nOK\n")
+				idling, pending = false, false
+			}
+		}
+	})
+
+	idle := make(chan SubSystem)
+	go func() {
+		s, err := c.Idle()
+		if err != nil {
+			t.Error(err)
+		}
+		idle <- s
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			s, err := c.Status()
+			if err != nil {
+				t.Error(err)
+			} else if s.Volume != 42 || s.State != Playing {
+				t.Errorf("Status: got corrupted response %+v", s)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			songs, err := c.PlaylistInfo(-1)
+			if err != nil {
+				t.Error(err)
+			} else if len(songs) != 2 || songs[1].File != "b.mp3" {
+				t.Errorf("PlaylistInfo: got corrupted response %+v", songs)
+			}
+		}()
+	}
+	wg.Wait()
+
+	notify <- struct{}{}
+
+	select {
+	case s := <-idle:
+		if s != PlayerSystem {
+			t.Fatalf("Idle: got subsystem %v, want %v", s, PlayerSystem)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Idle did not return")
+	}
+}
+
+func TestConcurrentIdles(t *testing.T) {
+	var idles atomic.Int32
+	notify := make(chan struct{})
+
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		lines := make(chan string)
+		go func() {
+			defer close(lines)
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				lines <- strings.TrimSpace(line)
+			}
+		}()
+
+		idling := false
+		for {
+			select {
+			case <-notify:
+				if idling {
+					io.WriteString(w, "changed: player\nOK\n")
+					idling = false
+				}
+			case line, ok := <-lines:
+				if !ok {
+					return
+				}
+
+				switch {
+				case strings.HasPrefix(line, "idle"):
+					idles.Add(1)
+					idling = true
+				case line == "noidle" && idling:
+					io.WriteString(w, "OK\n")
+					idling = false
+				}
+			}
+		}
+	})
+
+	// The second idle waits for more subsystems than the first, so it
+	// replaces it once. After that, neither may interrupt the other.
+	results := make(chan []SubSystem, 2)
+	for _, subsystems := range [][]SubSystem{{PlayerSystem}, nil} {
+		go func() {
+			list, err := c.WithContext(context.Background()).IdleSubSystems(subsystems...)
+			if err != nil {
+				t.Error(err)
+			}
+			results <- list
+		}()
+	}
+
+	time.Sleep(100 * time.Millisecond)
+
+	if n := idles.Load(); n > 2 {
+		t.Fatalf("sent %d idle commands, want at most 2", n)
+	}
+
+	notify <- struct{}{}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case list := <-results:
+			if len(list) != 1 || list[0] != PlayerSystem {
+				t.Fatalf("Idle: got %v, want [player]", list)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Idle did not return")
+		}
+	}
+}
+
+func TestCommandList(t *testing.T) {
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		var cmds []string
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			if line = strings.TrimSpace(line); line != "command_list_end" {
+				cmds = append(cmds, line)
+				continue
+			}
+
+			for i, cmd := range cmds[1:] {
+				switch {
+				case strings.HasPrefix(cmd, "addid"):
+					io.WriteString(w, "Id: 7\nlist_OK\n")
+				case strings.HasPrefix(cmd, "add"):
+					io.WriteString(w, "list_OK\n")
+				default:
+					io.WriteString(w, "ACK [50@"+strconv.Itoa(i)+"] {delete} Bad song index\n")
+					goto next
+				}
+			}
+			io.WriteString(w, "OK\n")
+		next:
+			cmds = nil
+		}
+	})
+
+	list := c.CommandList()
+	add := list.Add("a.mp3")
+	id := list.AddId("b.mp3", -1)
+
+	if err := list.End(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := add.Err(); err != nil {
+		t.Errorf("Add: %v", err)
+	}
+
+	if v, err := id.Value(); err != nil || v != 7 {
+		t.Errorf("AddId: got %d, %v; want 7", v, err)
+	}
+
+	add = list.Add("a.mp3")
+	del := list.Delete(99)
+	stop := list.Stop()
+
+	var e *Error
+	if err := list.End(); !errors.As(err, &e) || e.Index != 1 || e.Code != ErrNoExist {
+		t.Fatalf("End: got error %v, want failure at index 1", err)
+	}
+
+	if add.Err() != nil || del.Err() == nil || stop.Err() != ErrNotExecuted {
+		t.Errorf("unexpected results: %v, %v, %v", add.Err(), del.Err(), stop.Err())
+	}
+}
+
+func TestIdleSubSystems(t *testing.T) {
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		if line, _ := r.ReadString('\n'); line == "idle \"player\" \"mixer\" \"partition\"\n" {
+			io.WriteString(w, "changed: mixer\nchanged: player\nchanged: partition\nOK\n")
+		}
+	})
+
+	list, err := c.IdleSubSystems(PlayerSystem, MixerSystem, PartitionSystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SubSystem{MixerSystem, PlayerSystem, PartitionSystem}
+	if len(list) != len(want) {
+		t.Fatalf("IdleSubSystems: got %v, want %v", list, want)
+	}
+
+	for i := range want {
+		if list[i] != want[i] {
+			t.Fatalf("IdleSubSystems: got %v, want %v", list, want)
+		}
+	}
+}
+
+func TestIdleUnknown(t *testing.T) {
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		r.ReadString('\n')
+		io.WriteString(w, "changed: hologram\nOK\n")
+
+		if line, _ := r.ReadString('\n'); line == "idle\n" {
+			io.WriteString(w, "changed: options\nOK\n")
+		}
+	})
+
+	if s, err := c.Idle(); err != nil || s != OptionsSystem {
+		t.Fatalf("Idle: got %v, %v; want %v", s, err, OptionsSystem)
+	}
+}
+
+func TestSeqBreak(t *testing.T) {
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			switch strings.TrimSpace(line) {
+			case `listallinfo "music"`:
+				for i := 0; i < 100; i++ {
+					io.WriteString(w, "file: music/"+strconv.Itoa(i)+".mp3\nTitle: x\n")
+				}
+				io.WriteString(w, "OK\n")
+			case "ping":
+				io.WriteString(w, "OK\n")
+			}
+		}
+	})
+
+	var n int
+	for song, err := range c.ListInfoSeq("music") {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if want := "music/" + strconv.Itoa(n) + ".mp3"; song.File != want {
+			t.Fatalf("got song %q, want %q", song.File, want)
+		}
+
+		if n++; n == 3 {
+			break
+		}
+	}
+
+	// The rest of the response must have been drained.
+	if a, err := c.request("ping"); err != nil || len(a) != 0 {
+		t.Fatalf("connection out of sync after break: %v, %v", a, err)
+	}
+}
+
+func TestAlbumArt(t *testing.T) {
+	image := "\x89PNG\r\n\x1a\n" + strings.Repeat("\nOK\n", 5)
+
+	c := pipeClient(t, func(r *bufio.Reader, w net.Conn) {
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			var offset int
+			if _, err := fmt.Sscanf(line, "albumart \"a/b.flac\" %d\n", &offset); err != nil {
+				io.WriteString(w, "ACK [5@0] {} unknown command\n")
+				continue
+			}
+
+			end := offset + 10
+			if end > len(image) {
+				end = len(image)
+			}
+
+			fmt.Fprintf(w, "size: %d\nbinary: %d\n%s\nOK\n", len(image), end-offset, image[offset:end])
+		}
+	})
+
+	data, mime, err := c.AlbumArt("a/b.flac")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != image || mime != "image/png" {
+		t.Fatalf("AlbumArt: got %q (%s), want %q (image/png)", data, mime, image)
+	}
+}
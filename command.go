@@ -0,0 +1,127 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Command builds a single line of the protocol: a command name followed by
+// its arguments. Arguments are encoded the way the server expects them, so
+// commands built this way can carry any path or tag value.
+//
+// Strings are quoted and only '"' and '\' are escaped with a backslash. All
+// other bytes, including non-ASCII and control characters, are sent as they
+// are. Go's %q verb must not be used instead; the server does not know its
+// escape sequences.
+//
+// Integers are sent as decimal numbers and booleans as 1 or 0. Values of
+// other types are formatted with fmt.Sprint and quoted like strings.
+//
+// A newline ends the command, so no argument can contain one. Such commands
+// are never sent; see Err.
+type Command struct {
+	name string
+	args []string
+	err  error
+}
+
+var errNewline = errors.New("Command argument contains a newline.")
+
+// NewCommand returns a command with the given name and arguments.
+func NewCommand(name string, arg ...interface{}) *Command {
+	c := &Command{name: name}
+	return c.Arg(arg...)
+}
+
+// Arg appends arguments to the command and returns it.
+func (c *Command) Arg(arg ...interface{}) *Command {
+	for _, v := range arg {
+		s := encodeArg(v)
+		if c.err == nil && strings.IndexByte(s, '\n') > -1 {
+			c.err = errNewline
+		}
+		c.args = append(c.args, s)
+	}
+	return c
+}
+
+// Err returns the error found while encoding the arguments, if any. A
+// command with an error must not be sent.
+func (c *Command) Err() error {
+	return c.err
+}
+
+// String returns the command as it is sent to the server, without the
+// terminating newline.
+func (c *Command) String() string {
+	if len(c.args) == 0 {
+		return c.name
+	}
+	return c.name + " " + strings.Join(c.args, " ")
+}
+
+// Quote returns s as a quoted command argument.
+func Quote(s string) string {
+	var b strings.Builder
+
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	writeEscaped(&b, s)
+	b.WriteByte('"')
+	return b.String()
+}
+
+// writeEscaped writes s to b with a backslash before every '"' and '\'.
+func writeEscaped(b *strings.Builder, s string) {
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+}
+
+func encodeArg(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return Quote(v)
+	case int:
+		return strconv.Itoa(v)
+	case int8:
+		return strconv.FormatInt(int64(v), 10)
+	case int16:
+		return strconv.FormatInt(int64(v), 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case fmt.Stringer:
+		return Quote(v.String())
+	}
+	return Quote(fmt.Sprint(v))
+}
@@ -0,0 +1,38 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import "testing"
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		cmd  *Command
+		want string
+	}{
+		{NewCommand("status"), `status`},
+		{NewCommand("add", "Björk/Homogenic/01 Hunter.flac"), `add "Björk/Homogenic/01 Hunter.flac"`},
+		{NewCommand("add", `say "hi"\tab`), `add "say \"hi\"\\tab"`},
+		{NewCommand("add", "a\tb\x00c"), "add \"a\tb\x00c\""},
+		{NewCommand("seek", 3, int64(120)), `seek 3 120`},
+		{NewCommand("pause", true), `pause 1`},
+		{NewCommand("seekcur", 1.5), `seekcur 1.5`},
+		{NewCommand("idle", PlayerSystem), `idle "player"`},
+		{NewCommand("playlistmove", "list").Arg(1, 2), `playlistmove "list" 1 2`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cmd.String(); got != tt.want {
+			t.Errorf("got %q; want %q", got, tt.want)
+		}
+
+		if err := tt.cmd.Err(); err != nil {
+			t.Errorf("%q: unexpected error %v", tt.want, err)
+		}
+	}
+
+	if err := NewCommand("add", "a", "b\nc").Err(); err != errNewline {
+		t.Errorf("got error %v; want %v", err, errNewline)
+	}
+}
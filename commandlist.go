@@ -0,0 +1,265 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotExecuted is the error held by the results of commands which were
+// skipped because an earlier command in the same list failed.
+var ErrNotExecuted = errors.New("Command was not executed.")
+
+// Result holds the outcome of a single command in a CommandList. It becomes
+// available once the list has been sent with CommandList.End.
+type Result[T any] struct {
+	value T
+	err   error
+	done  bool
+}
+
+// Value returns the value produced by the command, or the error it failed
+// with.
+func (r *Result[T]) Value() (T, error) {
+	if !r.done {
+		var zero T
+		return zero, errors.New("Command list has not been sent.")
+	}
+	return r.value, r.err
+}
+
+// Err returns the error the command failed with, if any.
+func (r *Result[T]) Err() error {
+	_, err := r.Value()
+	return err
+}
+
+// CommandList collects commands and sends them to the server in a single
+// command_list_ok_begin ... command_list_end block. This saves a round trip
+// per command:
+//
+//     list := c.CommandList()
+//     for _, path := range paths {
+//         list.Add(path)
+//     }
+//     status := list.Status()
+//
+//     if err := list.End(); err != nil {
+//         ...
+//     }
+//
+// The server stops at the first command that fails. End then returns an
+// *Error whose Index is the position of that command in the list.
+type CommandList struct {
+	c    *Client
+	cmds []listCommand
+}
+
+type listCommand struct {
+	cmd   *Command
+	parse func(a Args)    // Stores the response of the command.
+	fail  func(err error) // Stores the error the command failed with.
+}
+
+// CommandList starts a new, empty command list.
+func (c *Client) CommandList() *CommandList {
+	return &CommandList{c: c}
+}
+
+// Len returns the number of commands in the list.
+func (l *CommandList) Len() int {
+	return len(l.cmds)
+}
+
+// End sends all collected commands and reads their responses. The list is
+// empty afterwards and may be reused.
+//
+// If an argument of any command contains a newline, nothing is sent. That
+// command fails with the error End returns and the others are not executed.
+func (l *CommandList) End() (err error) {
+	cmds := l.cmds
+	l.cmds = nil
+
+	if len(cmds) == 0 {
+		return
+	}
+
+	for i := range cmds {
+		if err = cmds[i].cmd.Err(); err != nil {
+			break
+		}
+	}
+
+	if err != nil {
+		for _, lc := range cmds {
+			if lc.cmd.Err() != nil {
+				lc.fail(lc.cmd.Err())
+			} else {
+				lc.fail(ErrNotExecuted)
+			}
+		}
+		return
+	}
+
+	var body strings.Builder
+	body.WriteString("command_list_ok_begin\n")
+	for _, lc := range cmds {
+		body.WriteString(lc.cmd.String())
+		body.WriteString("\n")
+	}
+	body.WriteString("command_list_end")
+
+	var n int
+
+	err = l.c.exchange(func() (err error) {
+		if err = l.c.send(body.String()); err != nil {
+			return
+		}
+
+		var key, value, term string
+		var data Args
+
+		for {
+			if key, value, term, err = l.c.readPair(); err != nil {
+				return
+			}
+
+			switch term {
+			case "":
+				data = append(data, Field{key, value})
+			case "list_OK":
+				if n < len(cmds) {
+					cmds[n].parse(data)
+				}
+				data = nil
+				n++
+			default:
+				return
+			}
+		}
+	})
+
+	for i := n; i < len(cmds); i++ {
+		if i == n && err != nil {
+			cmds[i].fail(err)
+		} else {
+			cmds[i].fail(ErrNotExecuted)
+		}
+	}
+
+	return
+}
+
+// queue adds a command whose response is discarded.
+func (l *CommandList) queue(cmd string, arg ...interface{}) *Result[struct{}] {
+	return queueParse(l, func(Args) struct{} { return struct{}{} }, cmd, arg...)
+}
+
+// queueParse adds a command whose response is turned into a value by parse.
+func queueParse[T any](l *CommandList, parse func(Args) T, cmd string, arg ...interface{}) *Result[T] {
+	r := new(Result[T])
+
+	l.cmds = append(l.cmds, listCommand{
+		cmd: NewCommand(cmd, arg...),
+		parse: func(a Args) {
+			r.value = parse(a)
+			r.done = true
+		},
+		fail: func(err error) {
+			r.err = err
+			r.done = true
+		},
+	})
+
+	return r
+}
+
+// Command adds an arbitrary command to the list. Its arguments are encoded
+// as described for the Command type. Its result holds the raw response.
+func (l *CommandList) Command(cmd string, arg ...interface{}) *Result[Args] {
+	return queueParse(l, func(a Args) Args { return a }, cmd, arg...)
+}
+
+// Add queues the 'add' command. See Client.Add.
+func (l *CommandList) Add(path string) *Result[struct{}] {
+	return l.queue("add", path)
+}
+
+// AddId queues the 'addid' command. Its result holds the id of the new
+// song. See Client.AddId.
+func (l *CommandList) AddId(path string, pos int) *Result[int] {
+	id := func(a Args) int { return a.I("Id") }
+	if pos > -1 {
+		return queueParse(l, id, "addid", path, pos)
+	}
+	return queueParse(l, id, "addid", path)
+}
+
+// Clear queues the 'clear' command. See Client.Clear.
+func (l *CommandList) Clear() *Result[struct{}] {
+	return l.queue("clear")
+}
+
+// Delete queues the 'delete' command. See Client.Delete.
+func (l *CommandList) Delete(pos int) *Result[struct{}] {
+	return l.queue("delete", pos)
+}
+
+// DeleteId queues the 'deleteid' command. See Client.DeleteId.
+func (l *CommandList) DeleteId(id int) *Result[struct{}] {
+	return l.queue("deleteid", id)
+}
+
+// Move queues the 'move' command. See Client.Move.
+func (l *CommandList) Move(src, dst int) *Result[struct{}] {
+	return l.queue("move", src, dst)
+}
+
+// MoveId queues the 'moveid' command. See Client.MoveId.
+func (l *CommandList) MoveId(src, dst int) *Result[struct{}] {
+	return l.queue("moveid", src, dst)
+}
+
+// PlaylistAdd queues the 'playlistadd' command. See Client.PlaylistAdd.
+func (l *CommandList) PlaylistAdd(name, path string) *Result[struct{}] {
+	return l.queue("playlistadd", name, path)
+}
+
+// PlaylistClear queues the 'playlistclear' command. See
+// Client.PlaylistClear.
+func (l *CommandList) PlaylistClear(name string) *Result[struct{}] {
+	return l.queue("playlistclear", name)
+}
+
+// Play queues the 'play' command. See Client.Play.
+func (l *CommandList) Play(pos int) *Result[struct{}] {
+	return l.queue("play", pos)
+}
+
+// PlayId queues the 'playid' command. See Client.PlayId.
+func (l *CommandList) PlayId(id int) *Result[struct{}] {
+	return l.queue("playid", id)
+}
+
+// Stop queues the 'stop' command. See Client.Stop.
+func (l *CommandList) Stop() *Result[struct{}] {
+	return l.queue("stop")
+}
+
+// Status queues the 'status' command. See Client.Status.
+func (l *CommandList) Status() *Result[*Status] {
+	return queueParse(l, readStatus, "status")
+}
+
+// Stats queues the 'stats' command. See Client.Stats.
+func (l *CommandList) Stats() *Result[*Stats] {
+	return queueParse(l, readStats, "stats")
+}
+
+// Current queues the 'currentsong' command. See Client.Current.
+func (l *CommandList) Current() *Result[*Song] {
+	return queueParse(l, readCurrentSong, "currentsong")
+}
@@ -0,0 +1,121 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrorCode is the numeric code of an error reported by the server. The
+// codes themselves satisfy the error interface, so they can be used with
+// errors.Is:
+//
+//     if errors.Is(err, mpd.ErrNoExist) {
+//         ...
+//     }
+type ErrorCode int
+
+const (
+	ErrNotList       ErrorCode = 1
+	ErrArg           ErrorCode = 2
+	ErrPassword      ErrorCode = 3
+	ErrPermission    ErrorCode = 4
+	ErrUnknown       ErrorCode = 5
+	ErrNoExist       ErrorCode = 50
+	ErrPlaylistMax   ErrorCode = 51
+	ErrSystem        ErrorCode = 52
+	ErrPlaylistLoad  ErrorCode = 53
+	ErrUpdateAlready ErrorCode = 54
+	ErrPlayerSync    ErrorCode = 55
+	ErrExist         ErrorCode = 56
+)
+
+func (e ErrorCode) Error() string {
+	switch e {
+	case ErrNotList:
+		return "not a command list"
+	case ErrArg:
+		return "invalid argument"
+	case ErrPassword:
+		return "invalid password"
+	case ErrPermission:
+		return "permission denied"
+	case ErrUnknown:
+		return "unknown command"
+	case ErrNoExist:
+		return "no such object"
+	case ErrPlaylistMax:
+		return "playlist is at the maximum size"
+	case ErrSystem:
+		return "system error"
+	case ErrPlaylistLoad:
+		return "playlist could not be loaded"
+	case ErrUpdateAlready:
+		return "already updating"
+	case ErrPlayerSync:
+		return "player is out of sync"
+	case ErrExist:
+		return "object already exists"
+	}
+	return "error " + strconv.Itoa(int(e))
+}
+
+// Error is an error reported by the server in an ACK line:
+//
+//     ACK [errcode@index] {command} message
+//
+// Index is the position of the failing command in a command list, and zero
+// for commands sent on their own.
+type Error struct {
+	Code    ErrorCode
+	Index   int
+	Command string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if len(e.Command) == 0 {
+		return e.Message
+	}
+	return e.Command + ": " + e.Message
+}
+
+// Is reports whether target is the ErrorCode of e.
+func (e *Error) Is(target error) bool {
+	code, ok := target.(ErrorCode)
+	return ok && code == e.Code
+}
+
+// parseAck parses an ACK line. Lines which do not follow the expected format
+// are reported with their text as message and ErrUnknown as code.
+func parseAck(line string) *Error {
+	e := &Error{Code: ErrUnknown}
+	line = strings.TrimSpace(strings.TrimPrefix(line, "ACK"))
+
+	// sig: [errcode@index] {command} message
+	//  ex: [2@0] {enableoutput} wrong number of arguments for "enableoutput"
+	if strings.HasPrefix(line, "[") {
+		if pos := strings.Index(line, "]"); pos > -1 {
+			var code, index int
+			if _, err := fmt.Sscanf(line[1:pos], "%d@%d", &code, &index); err == nil {
+				e.Code = ErrorCode(code)
+				e.Index = index
+			}
+			line = strings.TrimSpace(line[pos+1:])
+		}
+	}
+
+	if strings.HasPrefix(line, "{") {
+		if pos := strings.Index(line, "}"); pos > -1 {
+			e.Command = line[1:pos]
+			line = strings.TrimSpace(line[pos+1:])
+		}
+	}
+
+	e.Message = line
+	return e
+}
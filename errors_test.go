@@ -0,0 +1,57 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseAck(t *testing.T) {
+	tests := []struct {
+		line string
+		want Error
+	}{
+		{
+			`ACK [2@0] {enableoutput} wrong number of arguments for "enableoutput"`,
+			Error{ErrArg, 0, "enableoutput", `wrong number of arguments for "enableoutput"`},
+		},
+		{
+			`ACK [50@3] {add} No such directory`,
+			Error{ErrNoExist, 3, "add", "No such directory"},
+		},
+		{
+			`ACK [5@0] {} unknown command "foo"`,
+			Error{ErrUnknown, 0, "", `unknown command "foo"`},
+		},
+		{
+			`ACK something odd`,
+			Error{ErrUnknown, 0, "", "something odd"},
+		},
+	}
+
+	for _, tt := range tests {
+		if e := parseAck(tt.line); *e != tt.want {
+			t.Errorf("parseAck(%q) = %+v; want %+v", tt.line, *e, tt.want)
+		}
+	}
+}
+
+func TestErrorIs(t *testing.T) {
+	var err error = parseAck(`ACK [56@0] {save} Playlist already exists`)
+
+	if !errors.Is(err, ErrExist) {
+		t.Errorf("errors.Is(%v, ErrExist) = false; want true", err)
+	}
+
+	if errors.Is(err, ErrNoExist) {
+		t.Errorf("errors.Is(%v, ErrNoExist) = true; want false", err)
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || e.Command != "save" {
+		t.Errorf("errors.As(%v) did not yield the command", err)
+	}
+}
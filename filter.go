@@ -0,0 +1,197 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Filter is a filter expression, as accepted by find, search, count and
+// similar commands since protocol version 0.21. Filters are built with the
+// functions below and combined with And and Not:
+//
+//     f := And(Eq("artist", "Tool"), Not(Contains("title", "live")))
+//
+// This renders as:
+//
+//     ((artist == 'Tool') AND (!(title contains 'live')))
+//
+// Values are escaped as the server expects, so they may hold any character.
+// The tag "any" matches any tag, and "file" matches the path of a song.
+//
+// The zero Filter is empty. Commands which require a filter, such as find
+// and search, reject it without contacting the server.
+type Filter struct {
+	expr string
+	need Version // Oldest protocol version supporting the expression.
+	err  error   // Set when the filter was built from an empty one.
+}
+
+// FilterExpr returns a filter holding expr as it is. It allows the use of
+// expressions which have no function of their own.
+func FilterExpr(expr string) Filter {
+	return Filter{expr: expr, need: versionFilter}
+}
+
+// Eq matches songs whose tag has the given value.
+func Eq(tag, value string) Filter { return compare(tag, "==", value) }
+
+// Ne matches songs whose tag does not have the given value.
+func Ne(tag, value string) Filter { return compare(tag, "!=", value) }
+
+// Contains matches songs whose tag contains value.
+func Contains(tag, value string) Filter { return compare(tag, "contains", value) }
+
+// StartsWith matches songs whose tag starts with value. It requires protocol
+// version 0.24.
+func StartsWith(tag, value string) Filter {
+	f := compare(tag, "starts_with", value)
+	f.need = versionStartsWith
+	return f
+}
+
+// Match matches songs whose tag matches the regular expression re. The
+// server uses Perl compatible regular expressions.
+func Match(tag, re string) Filter { return compare(tag, "=~", re) }
+
+// NotMatch matches songs whose tag does not match the regular expression re.
+func NotMatch(tag, re string) Filter { return compare(tag, "!~", re) }
+
+// Base matches songs inside the directory at path, relative to the music
+// directory.
+func Base(path string) Filter {
+	return Filter{expr: "(base " + quoteFilter(path) + ")", need: versionFilter}
+}
+
+// ModifiedSince matches songs whose file was modified at or after t.
+func ModifiedSince(t time.Time) Filter {
+	return Filter{expr: "(modified-since " + quoteFilter(t.UTC().Format(time.RFC3339)) + ")", need: versionFilter}
+}
+
+// AddedSince matches songs added to the database at or after t. It requires
+// protocol version 0.24.
+func AddedSince(t time.Time) Filter {
+	return Filter{expr: "(added-since " + quoteFilter(t.UTC().Format(time.RFC3339)) + ")", need: versionAddedSince}
+}
+
+// AudioFormatEq matches songs with exactly the given audio format.
+func AudioFormatEq(f AudioFormat) Filter {
+	return compare("AudioFormat", "==", f.String())
+}
+
+// AudioFormatMatch matches songs whose audio format matches mask. Parts of
+// the mask may be "*", eg: "44100:*:2" matches all stereo songs sampled at
+// 44.1kHz.
+func AudioFormatMatch(mask string) Filter {
+	return compare("AudioFormat", "=~", mask)
+}
+
+// PrioAtLeast matches songs in the queue with a priority of at least prio.
+// It requires protocol version 0.24.
+func PrioAtLeast(prio int) Filter {
+	return Filter{expr: "(prio >= " + strconv.Itoa(prio) + ")", need: versionPrioFilter}
+}
+
+// Not matches songs which f does not match. Negating an empty filter is an
+// error, which is reported by the command the result is passed to.
+func Not(f Filter) Filter {
+	if f.err != nil {
+		return f
+	}
+
+	if len(f.expr) == 0 {
+		return Filter{err: errors.New("Cannot negate an empty filter.")}
+	}
+
+	return Filter{expr: "(!" + f.expr + ")", need: f.need}
+}
+
+// And matches songs which all of the given filters match. Empty filters are
+// left out; if all of them are empty, so is the result. A single filter is
+// returned as it is.
+func And(filters ...Filter) Filter {
+	var list []Filter
+	for _, v := range filters {
+		if v.err != nil {
+			return v
+		}
+
+		if len(v.expr) > 0 {
+			list = append(list, v)
+		}
+	}
+
+	switch len(list) {
+	case 0:
+		return Filter{}
+	case 1:
+		return list[0]
+	}
+
+	var b strings.Builder
+	f := Filter{need: versionFilter}
+
+	b.WriteByte('(')
+	for i, v := range list {
+		if i > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(v.expr)
+
+		if !f.need.AtLeast(v.need) {
+			f.need = v.need
+		}
+	}
+	b.WriteByte(')')
+
+	f.expr = b.String()
+	return f
+}
+
+// check reports why f can not be sent to the server, if it can not. Empty
+// filters are only accepted if optional is set.
+func (f Filter) check(optional bool) error {
+	if f.err != nil {
+		return f.err
+	}
+
+	if len(f.expr) == 0 && !optional {
+		return errors.New("Filter is empty.")
+	}
+
+	return nil
+}
+
+// String returns the filter expression. It is quoted once more when sent as
+// a command argument.
+func (f Filter) String() string {
+	return f.expr
+}
+
+// compare returns the expression '(tag op 'value')'.
+func compare(tag, op, value string) Filter {
+	return Filter{expr: "(" + tag + " " + op + " " + quoteFilter(value) + ")", need: versionFilter}
+}
+
+// quoteFilter quotes a value inside a filter expression. Quotes and
+// backslashes are escaped with a backslash.
+func quoteFilter(s string) string {
+	var b strings.Builder
+
+	b.Grow(len(s) + 2)
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\'', '"', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
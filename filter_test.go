@@ -0,0 +1,156 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jteeuwen/go-pkg-mpd/mpdtest"
+)
+
+func TestFilterString(t *testing.T) {
+	for _, tt := range []struct {
+		f    Filter
+		want string
+	}{
+		{Eq("artist", "Tool"), `(artist == 'Tool')`},
+		{Ne("album", `O'Brien "Live" \o/`), `(album != 'O\'Brien \"Live\" \\o/')`},
+		{And(Eq("artist", "Tool"), Not(Contains("title", "live"))),
+			`((artist == 'Tool') AND (!(title contains 'live')))`},
+		{And(StartsWith("title", "Eon")), `(title starts_with 'Eon')`},
+		{Match("genre", "^Prog.*$"), `(genre =~ '^Prog.*$')`},
+		{NotMatch("genre", "Pop"), `(genre !~ 'Pop')`},
+		{Base("Tool/Lateralus"), `(base 'Tool/Lateralus')`},
+		{ModifiedSince(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)), `(modified-since '2020-01-02T03:04:05Z')`},
+		{AudioFormatEq(AudioFormat{SampleRate: 44100, Bits: 16, Channels: 2}), `(AudioFormat == '44100:16:2')`},
+		{AudioFormatMatch("*:24:*"), `(AudioFormat =~ '*:24:*')`},
+		{PrioAtLeast(42), `(prio >= 42)`},
+	} {
+		if got := tt.f.String(); got != tt.want {
+			t.Errorf("got %s, want %s", got, tt.want)
+		}
+	}
+
+	// The expression is escaped once more as a command argument.
+	cmd := NewCommand("find", Eq("album", `O'Brien "Live"`)).String()
+	if want := `find "(album == 'O\\'Brien \\\"Live\\\"')"`; cmd != want {
+		t.Errorf("got %s, want %s", cmd, want)
+	}
+
+	if v := And(Eq("a", "b"), StartsWith("c", "d")).need; v != versionStartsWith {
+		t.Errorf("got required version %v", v)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddSong("Misc/O'Brien.mp3", "Artist", `O'Brien "Jr"`, "Title", `Back\slash`, "Format", "48000:24:2")
+
+	c := dialTestServer(t, srv)
+
+	songs, err := c.FindFilter(And(Eq("artist", "Tool"), Not(Contains("title", "Grudge"))))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(songs) != 1 || songs[0].Title != "Eon Blue Apocalypse" {
+		t.Fatalf("unexpected result: %+v", songs)
+	}
+
+	songs, err = c.FindFilter(And(Eq("artist", `O'Brien "Jr"`), Eq("title", `Back\slash`)))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(songs) != 1 || songs[0].File != "Misc/O'Brien.mp3" {
+		t.Fatalf("unexpected result for escaped values: %+v", songs)
+	}
+
+	songs, err = c.SearchFilter(Eq("artist", "tool"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(songs) != 2 {
+		t.Fatalf("search is not case insensitive: %+v", songs)
+	}
+
+	n, _, err := c.CountFilter(AudioFormatMatch("*:24:*"))
+	if err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("got %d songs with 24 bit samples, want 1", n)
+	}
+
+	n, _, err = c.CountFilter(And(Base("Tool"), Match("title", "^E")))
+	if err != nil {
+		t.Fatal(err)
+	} else if n != 1 {
+		t.Fatalf("got %d songs in Tool starting with E, want 1", n)
+	}
+
+	if err = c.Add("Tool"); err != nil {
+		t.Fatal(err)
+	}
+
+	if songs, err = c.PlaylistSearchFilter(Contains("title", "grudge")); err != nil {
+		t.Fatal(err)
+	} else if len(songs) != 1 {
+		t.Fatalf("unexpected playlist search result: %+v", songs)
+	}
+
+	// The server announces 0.23.5.
+	if _, err = c.FindFilter(StartsWith("title", "Eon")); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("got %v, want unsupported error", err)
+	}
+}
+
+func TestEmptyFilter(t *testing.T) {
+	srv := newTestServer(t)
+	sent := 0
+	for _, name := range []string{"find", "search", "count", "findadd"} {
+		srv.Handle(name, func(r *mpdtest.Response, args []string) error {
+			sent++
+			return nil
+		})
+	}
+
+	c := dialTestServer(t, srv)
+
+	if f := And(); f.String() != "" || f.need != (Version{}) {
+		t.Errorf("And() = %q requiring %v, want an empty filter", f, f.need)
+	}
+
+	if f := And(Filter{}, Eq("artist", "Tool"), And()); f.String() != `(artist == 'Tool')` {
+		t.Errorf("empty filters were not left out of And: %s", f)
+	}
+
+	for _, f := range []Filter{{}, And(), And(Filter{}, Filter{}), Not(Filter{}), Not(And()), And(Eq("a", "b"), Not(Filter{}))} {
+		if _, err := c.FindFilter(f); err == nil {
+			t.Errorf("FindFilter(%q) succeeded", f)
+		}
+
+		if _, err := c.SearchFilter(f, Sort("title")); err == nil {
+			t.Errorf("SearchFilter(%q) succeeded", f)
+		}
+
+		if _, _, err := c.CountFilter(f); err == nil {
+			t.Errorf("CountFilter(%q) succeeded", f)
+		}
+
+		if err := c.FindAdd(f); err == nil {
+			t.Errorf("FindAdd(%q) succeeded", f)
+		}
+	}
+
+	if sent != 0 {
+		t.Errorf("%d empty filters were sent to the server", sent)
+	}
+
+	// List and count take an optional filter; only an invalid one fails.
+	if _, err := c.ListFilter("album", And()); err != nil {
+		t.Errorf("ListFilter with an empty filter: %v", err)
+	}
+
+	if _, err := c.CountGroup("artist", Not(Filter{})); err == nil {
+		t.Error("CountGroup with a negated empty filter succeeded")
+	}
+}
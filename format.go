@@ -0,0 +1,107 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// AudioFormat describes a stream of audio, as reported in the 'Format' tag
+// of songs and the 'audio' field of the status. The server writes it as
+// "samplerate:bits:channels", eg: "44100:24:2". Floating point samples have
+// "f" for bits, and DSD streams are written as "dsd64:2".
+type AudioFormat struct {
+	// SampleRate is the number of samples per second and channel. For DSD
+	// this is the bit rate of a single channel, eg: 2822400 for DSD64.
+	SampleRate int
+
+	// Bits is the number of bits per sample: 8, 16, 24 or 32. It is zero
+	// if Float or DSD is set.
+	Bits int
+
+	Float    bool // Samples are 32 bit floating point numbers.
+	DSD      bool // The stream is 1 bit Direct Stream Digital.
+	Channels int
+}
+
+// dsdBase is the bit rate of DSD64 divided by 64.
+const dsdBase = 44100
+
+// ParseAudioFormat parses an audio format as sent by the server.
+func ParseAudioFormat(s string) (f AudioFormat, err error) {
+	parts := strings.Split(s, ":")
+	invalid := errors.New("Invalid audio format: " + s)
+
+	var rate string
+
+	switch {
+	case len(parts) == 2 && strings.HasPrefix(parts[0], "dsd"):
+		var n int
+		if n, err = strconv.Atoi(parts[0][3:]); err != nil || n <= 0 {
+			return AudioFormat{}, invalid
+		}
+		f.SampleRate = n * dsdBase
+		f.DSD = true
+
+	case len(parts) == 3:
+		rate = parts[0]
+		switch parts[1] {
+		case "f":
+			f.Float = true
+		case "dsd":
+			f.DSD = true
+		default:
+			if f.Bits, err = strconv.Atoi(parts[1]); err != nil || f.Bits <= 0 {
+				return AudioFormat{}, invalid
+			}
+		}
+
+		if f.SampleRate, err = strconv.Atoi(rate); err != nil || f.SampleRate <= 0 {
+			return AudioFormat{}, invalid
+		}
+
+		// The server counts DSD samples in bytes of 8 bits.
+		if f.DSD {
+			f.SampleRate *= 8
+		}
+
+	default:
+		return AudioFormat{}, invalid
+	}
+
+	if f.Channels, err = strconv.Atoi(parts[len(parts)-1]); err != nil || f.Channels <= 0 {
+		return AudioFormat{}, invalid
+	}
+
+	return f, nil
+}
+
+// String returns the format the way the server writes it. The zero value
+// yields an empty string.
+func (f AudioFormat) String() string {
+	ch := strconv.Itoa(f.Channels)
+
+	switch {
+	case f == AudioFormat{}:
+		return ""
+	case f.DSD && f.SampleRate%dsdBase == 0:
+		return "dsd" + strconv.Itoa(f.SampleRate/dsdBase) + ":" + ch
+	case f.DSD:
+		return strconv.Itoa(f.SampleRate/8) + ":dsd:" + ch
+	case f.Float:
+		return strconv.Itoa(f.SampleRate) + ":f:" + ch
+	}
+
+	return strconv.Itoa(f.SampleRate) + ":" + strconv.Itoa(f.Bits) + ":" + ch
+}
+
+// UnmarshalText parses an audio format as sent by the server. It allows
+// AudioFormat fields to be decoded with Unmarshal.
+func (f *AudioFormat) UnmarshalText(b []byte) (err error) {
+	*f, err = ParseAudioFormat(string(b))
+	return
+}
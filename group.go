@@ -0,0 +1,103 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// TagGroup is one value of a group tag in the result of ListGroups, along
+// with the values found for it.
+type TagGroup struct {
+	Tag    string      // Name of the group tag, eg: "AlbumArtist".
+	Value  string      // Value of the group tag. Empty for untagged songs.
+	Values []string    // Values of the listed tag; set for the innermost groups.
+	Groups []*TagGroup // Nested groups; set for all other groups.
+}
+
+// GroupCount holds the number of songs and their playtime for one value of
+// the group tag given to CountGroup.
+type GroupCount struct {
+	Value    string
+	Songs    int
+	Playtime int // Total playtime in seconds.
+}
+
+// readValues returns the values of all fields named tag. Tag names are not
+// case sensitive; the server answers with their canonical spelling.
+func readValues(a Args, tag string) (v []string) {
+	for _, f := range a {
+		if strings.EqualFold(f.Key, tag) {
+			v = append(v, f.Value)
+		}
+	}
+	return
+}
+
+// readTagGroups decodes a grouped list response. The server writes the
+// groups depth first; each group tag is written where its value changes,
+// and followed by the values of the listed tag or the next group tag.
+func readTagGroups(a Args, tag string) (list []*TagGroup) {
+	var stack []*TagGroup
+	levels := make(map[string]int)
+
+	for _, f := range a {
+		if strings.EqualFold(f.Key, tag) {
+			if len(stack) > 0 {
+				g := stack[len(stack)-1]
+				g.Values = append(g.Values, f.Value)
+			}
+			continue
+		}
+
+		// Group tags first appear from the outermost to the innermost.
+		key := strings.ToLower(f.Key)
+		level, ok := levels[key]
+		if !ok {
+			level = len(levels)
+			levels[key] = level
+		}
+
+		if level > len(stack) {
+			continue
+		}
+
+		g := &TagGroup{Tag: f.Key, Value: f.Value}
+		if level == 0 {
+			list = append(list, g)
+		} else {
+			parent := stack[level-1]
+			parent.Groups = append(parent.Groups, g)
+		}
+
+		stack = append(stack[:level], g)
+	}
+
+	return
+}
+
+// readGroupCounts decodes a grouped count response. Each group starts with
+// the group tag, followed by its songs and playtime.
+func readGroupCounts(a Args, group string) (list []GroupCount) {
+	for _, f := range a {
+		if strings.EqualFold(f.Key, group) {
+			list = append(list, GroupCount{Value: f.Value})
+			continue
+		}
+
+		if len(list) == 0 {
+			continue
+		}
+
+		switch g := &list[len(list)-1]; f.Key {
+		case "songs":
+			g.Songs, _ = strconv.Atoi(f.Value)
+		case "playtime":
+			g.Playtime, _ = strconv.Atoi(f.Value)
+		}
+	}
+	return
+}
@@ -0,0 +1,92 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReadTagGroups(t *testing.T) {
+	groups := readTagGroups(Args{
+		{"AlbumArtist", "A"},
+		{"Date", "2001"},
+		{"Album", "X"},
+		{"Album", "Y"},
+		{"Date", "2005"},
+		{"Album", "Z"},
+		{"AlbumArtist", "B"},
+		{"Date", ""},
+		{"Album", "W"},
+	}, "album")
+
+	if len(groups) != 2 || groups[0].Value != "A" || groups[1].Value != "B" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+
+	a := groups[0]
+	if a.Tag != "AlbumArtist" || len(a.Groups) != 2 || a.Values != nil {
+		t.Fatalf("unexpected group: %+v", a)
+	}
+
+	if d := a.Groups[0]; d.Tag != "Date" || d.Value != "2001" || !reflect.DeepEqual(d.Values, []string{"X", "Y"}) {
+		t.Fatalf("unexpected nested group: %+v", d)
+	}
+
+	if d := groups[1].Groups[0]; d.Value != "" || !reflect.DeepEqual(d.Values, []string{"W"}) {
+		t.Fatalf("unexpected nested group: %+v", d)
+	}
+}
+
+func TestListAndCount(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddSong("Tool/Aenima/01 Stinkfist.flac",
+		"Artist", "Tool", "Album", "Ænima", "Date", "1996", "duration", "311.2")
+
+	c := dialTestServer(t, srv)
+
+	artists, err := c.List("artist", "", "")
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(artists, []string{"Björk", "Tool"}) {
+		t.Fatalf("got artists %q", artists)
+	}
+
+	albums, err := c.ListFilter("album", Eq("artist", "Tool"))
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(albums, []string{"Lateralus", "Ænima"}) {
+		t.Fatalf("got albums %q", albums)
+	}
+
+	groups, err := c.ListGroups("album", Filter{}, "artist", "date")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(groups) != 2 || groups[1].Value != "Tool" || len(groups[1].Groups) != 2 {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+
+	if d := groups[1].Groups[0]; d.Value != "1996" || !reflect.DeepEqual(d.Values, []string{"Ænima"}) {
+		t.Fatalf("unexpected nested group: %+v", d)
+	}
+
+	counts, err := c.CountGroup("artist", Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []GroupCount{{"Björk", 1, 0}, {"Tool", 3, 311}}
+	if !reflect.DeepEqual(counts, want) {
+		t.Fatalf("got counts %+v, want %+v", counts, want)
+	}
+
+	if counts, err = c.CountGroup("album", Eq("artist", "Tool")); err != nil {
+		t.Fatal(err)
+	} else if len(counts) != 2 || counts[0].Songs != 2 {
+		t.Fatalf("got counts %+v", counts)
+	}
+}
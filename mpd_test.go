@@ -4,32 +4,245 @@
 
 package mpd
 
-import "testing"
+import (
+	"errors"
+	"testing"
+
+	"github.com/jteeuwen/go-pkg-mpd/mpdtest"
+)
+
+// newTestServer starts a fake server holding a small library.
+func newTestServer(t *testing.T) *mpdtest.Server {
+	srv := mpdtest.NewServer()
+	t.Cleanup(func() { srv.Close() })
+
+	srv.AddSong("Tool/Lateralus/01 The Grudge.flac",
+		"Artist", "Tool", "Album", "Lateralus", "Title", "The Grudge",
+		"Track", "1", "Date", "2001")
+	srv.AddSong("Tool/Lateralus/02 Eon Blue Apocalypse.flac",
+		"Artist", "Tool", "Album", "Lateralus", "Title", "Eon Blue Apocalypse",
+		"Track", "2", "Date", "2001")
+	srv.AddSong("Björk/Homogenic/01 Hunter.flac",
+		"Artist", "Björk", "Album", "Homogenic", "Title", "Hunter",
+		"Track", "1", "Date", "1997")
+	return srv
+}
+
+// dialTestServer connects to srv.
+func dialTestServer(t *testing.T, srv *mpdtest.Server) *Client {
+	c, err := Dial(srv.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { c.Close() })
+	return c
+}
 
 func Test(t *testing.T) {
 	var err error
 	var c *Client
 
-	if c, err = Dial("127.0.0.1:6600", ""); err != nil {
+	srv := newTestServer(t)
+
+	if c, err = Dial(srv.Addr(), ""); err != nil {
 		t.Fatal(err)
 	}
 
 	defer c.Close()
 
+	if err = c.Add("Tool"); err != nil {
+		t.Fatal(err)
+	}
+
 	if status, err := c.Status(); err != nil {
 		t.Fatal(err)
-	} else {
-		// do something with our status data.
-		_ = status
+	} else if status.PlaylistLength != 2 {
+		t.Fatalf("got playlist length %d, want 2", status.PlaylistLength)
 	}
 
 	if songs, err := c.PlaylistSearch("artist", "tool"); err != nil {
 		t.Fatal(err)
-	} else {
-		var song *Song
-		for _, song = range songs {
-			// do something with our song data.
-			_ = song
+	} else if len(songs) != 2 || songs[1].Title != "Eon Blue Apocalypse" {
+		t.Fatalf("unexpected search result: %+v", songs)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetPassword("secret")
+
+	if _, err := Dial(srv.Addr(), "wrong"); err == nil {
+		t.Fatal("Dial succeeded with the wrong password")
+	}
+
+	c, err := NewClient(srv.Pipe(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if _, err = c.Status(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewline(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetPassword("secret")
+
+	if _, err := NewClient(srv.Pipe(), "secret\nstatus"); err != errNewline {
+		t.Fatalf("NewClient: got %v, want %v", err, errNewline)
+	}
+
+	c, err := NewClient(srv.Pipe(), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err = c.Add("Tool\nadd \"Björk\""); err != errNewline {
+		t.Fatalf("Add: got %v, want %v", err, errNewline)
+	}
+
+	if _, err = c.FindFilter(Eq("artist", "Tool\nclear")); err != errNewline {
+		t.Fatalf("FindFilter: got %v, want %v", err, errNewline)
+	}
+
+	list := c.CommandList()
+	add := list.Add("Tool")
+	bad := list.Add("Tool\nclear")
+
+	if err = list.End(); err != errNewline || add.Err() != ErrNotExecuted || bad.Err() != errNewline {
+		t.Fatalf("End: got %v, %v, %v", err, add.Err(), bad.Err())
+	}
+
+	// Nothing was sent, so the connection is still in sync.
+	if status, err := c.Status(); err != nil || status.PlaylistLength != 0 {
+		t.Fatalf("Status: got %+v, %v", status, err)
+	}
+
+	if songs, err := c.Find("album", "Lateralus"); err != nil || len(songs) != 2 {
+		t.Fatalf("Find: got %d songs, %v", len(songs), err)
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	c := dialTestServer(t, newTestServer(t))
+
+	songs, err := c.Find("album", "Lateralus")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(songs) != 2 || songs[0].Track != 1 || songs[1].Track != 2 {
+		t.Fatalf("Find: unexpected result %+v", songs)
+	}
+
+	files, err := c.ListFiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 3 || files[0] != "Björk/Homogenic/01 Hunter.flac" {
+		t.Fatalf("ListFiles: unexpected result %q", files)
+	}
+
+	if songs, _, err := c.Count("artist", "Tool"); err != nil || songs != 2 {
+		t.Fatalf("Count: got %d, %v; want 2", songs, err)
+	}
+}
+
+func TestPlayback(t *testing.T) {
+	srv := newTestServer(t)
+	c := dialTestServer(t, srv)
+
+	if song, err := c.Current(); err != nil || song != nil {
+		t.Fatalf("Current: got %+v, %v; want no song", song, err)
+	}
+
+	if err := c.Add("Tool/Lateralus"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Play(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Volume(40, false); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := c.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.State != Playing || s.Song != 1 || s.Volume != 40 {
+		t.Fatalf("Status: unexpected result %+v", s)
+	}
+
+	list := c.CommandList()
+	current := list.Current()
+	if err = list.End(); err != nil {
+		t.Fatal(err)
+	}
+
+	if song, err := current.Value(); err != nil || song == nil || song.Pos != 1 {
+		t.Fatalf("Current: got %+v, %v; want the second song", song, err)
+	}
+
+	if err = c.Toggle(); err != nil {
+		t.Fatal(err)
+	}
+
+	if state := srv.PlayerState(); state != "pause" {
+		t.Fatalf("got state %q after Toggle, want pause", state)
+	}
+}
+
+func TestStoredPlaylists(t *testing.T) {
+	srv := newTestServer(t)
+	c := dialTestServer(t, srv)
+
+	if err := c.PlaylistAdd("favourites", "Björk/Homogenic/01 Hunter.flac"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Save("favourites"); !errors.Is(err, ErrExist) {
+		t.Fatalf("Save: got error %v, want %v", err, ErrExist)
+	}
+
+	songs, err := c.ListPlaylistSongs("favourites")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(songs) != 1 || songs[0].Artist != "Björk" {
+		t.Fatalf("ListPlaylistSongs: unexpected result %+v", songs)
+	}
+}
+
+func TestIdleNotification(t *testing.T) {
+	srv := newTestServer(t)
+	c := dialTestServer(t, srv)
+
+	changed := make(chan []SubSystem)
+	go func() {
+		list, err := c.IdleSubSystems(PlaylistSystem, PlayerSystem)
+		if err != nil {
+			t.Error(err)
 		}
+		changed <- list
+	}()
+
+	// Runs while the idle is pending; the client interrupts and resumes it.
+	other := dialTestServer(t, srv)
+	if err := other.Add("Tool"); err != nil {
+		t.Fatal(err)
+	}
+
+	if list := <-changed; len(list) != 1 || list[0] != PlaylistSystem {
+		t.Fatalf("got changes %v, want [playlist]", list)
 	}
 }
@@ -0,0 +1,1537 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpdtest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// command is a built-in command. It runs with s.mu held.
+type command struct {
+	min, max int // Number of arguments; max is -1 for no limit.
+	fn       func(s *Server, r *Response, args []string) error
+}
+
+var commands map[string]command
+
+// connCommand is a command which acts on the state of the calling
+// connection.
+type connCommand struct {
+	min, max int
+	fn       func(s *Server, c *conn, r *Response, args []string) error
+}
+
+var connCommands = map[string]connCommand{
+	// Client to client messages
+	"subscribe":    {1, 1, (*Server).cmdSubscribe},
+	"unsubscribe":  {1, 1, (*Server).cmdUnsubscribe},
+	"channels":     {0, 0, (*Server).cmdChannels},
+	"readmessages": {0, 0, (*Server).cmdReadMessages},
+	"sendmessage":  {2, 2, (*Server).cmdSendMessage},
+
+	// Partitions
+	"partition":      {1, 1, (*Server).cmdPartition},
+	"listpartitions": {0, 0, (*Server).cmdListPartitions},
+	"newpartition":   {1, 1, (*Server).cmdNewPartition},
+	"delpartition":   {1, 1, (*Server).cmdDelPartition},
+	"moveoutput":     {1, 1, (*Server).cmdMoveOutput},
+}
+
+func init() {
+	commands = map[string]command{
+		// Connection
+		"ping":        {0, 0, cmdNop},
+		"binarylimit": {1, 1, cmdNop},
+		"commands":    {0, 0, cmdCommands},
+		"notcommands": {0, 0, cmdNop},
+		"tagtypes":    {0, -1, cmdTagTypes},
+		"urlhandlers": {0, 0, cmdURLHandlers},
+
+		// Status
+		"status":      {0, 0, (*Server).cmdStatus},
+		"stats":       {0, 0, (*Server).cmdStats},
+		"currentsong": {0, 0, (*Server).cmdCurrentSong},
+
+		// Playback options and control
+		"consume":   {1, 1, (*Server).cmdConsume},
+		"crossfade": {1, 1, (*Server).cmdCrossfade},
+		"random":    {1, 1, (*Server).cmdRandom},
+		"repeat":    {1, 1, (*Server).cmdRepeat},
+		"single":    {1, 1, (*Server).cmdSingle},
+		"setvol":    {1, 1, (*Server).cmdSetVol},
+		"next":      {0, 0, (*Server).cmdNext},
+		"previous":  {0, 0, (*Server).cmdPrevious},
+		"pause":     {0, 1, (*Server).cmdPause},
+		"play":      {0, 1, (*Server).cmdPlay},
+		"playid":    {0, 1, (*Server).cmdPlayId},
+		"stop":      {0, 0, (*Server).cmdStop},
+		"seek":      {2, 2, (*Server).cmdSeek},
+		"seekid":    {2, 2, (*Server).cmdSeekId},
+		"seekcur":   {1, 1, (*Server).cmdSeekCur},
+
+		// Queue
+		"add":            {1, 2, (*Server).cmdAdd},
+		"addid":          {1, 2, (*Server).cmdAddId},
+		"clear":          {0, 0, (*Server).cmdClear},
+		"delete":         {1, 1, (*Server).cmdDelete},
+		"deleteid":       {1, 1, (*Server).cmdDeleteId},
+		"move":           {2, 2, (*Server).cmdMove},
+		"moveid":         {2, 2, (*Server).cmdMoveId},
+		"swap":           {2, 2, (*Server).cmdSwap},
+		"swapid":         {2, 2, (*Server).cmdSwapId},
+		"shuffle":        {0, 1, (*Server).cmdShuffle},
+		"playlistinfo":   {0, 1, (*Server).cmdPlaylistInfo},
+		"playlistid":     {0, 1, (*Server).cmdPlaylistId},
+		"plchanges":      {1, 2, (*Server).cmdPlChanges},
+		"playlistfind":   {1, -1, (*Server).cmdPlaylistFind},
+		"playlistsearch": {1, -1, (*Server).cmdPlaylistSearch},
+
+		// Stored playlists
+		"listplaylists":    {0, 0, (*Server).cmdListPlaylists},
+		"listplaylist":     {1, 1, (*Server).cmdListPlaylist},
+		"listplaylistinfo": {1, 1, (*Server).cmdListPlaylistInfo},
+		"load":             {1, 2, (*Server).cmdLoad},
+		"save":             {1, 1, (*Server).cmdSave},
+		"rm":               {1, 1, (*Server).cmdRm},
+		"rename":           {2, 2, (*Server).cmdRename},
+		"playlistadd":      {2, 2, (*Server).cmdPlaylistAdd},
+		"playlistclear":    {1, 1, (*Server).cmdPlaylistClear},
+		"playlistdelete":   {2, 2, (*Server).cmdPlaylistDelete},
+		"playlistmove":     {3, 3, (*Server).cmdPlaylistMove},
+
+		// Database
+		"count":       {1, -1, (*Server).cmdCount},
+		"find":        {1, -1, (*Server).cmdFind},
+		"findadd":     {1, -1, (*Server).cmdFindAdd},
+		"search":      {1, -1, (*Server).cmdSearch},
+		"searchadd":   {1, -1, (*Server).cmdSearchAdd},
+		"searchaddpl": {2, -1, (*Server).cmdSearchAddPl},
+		"list":        {1, -1, (*Server).cmdList},
+		"listall":     {0, 1, (*Server).cmdListAll},
+		"listallinfo": {0, 1, (*Server).cmdListAllInfo},
+		"lsinfo":      {0, 1, (*Server).cmdLsInfo},
+		"update":      {0, 1, (*Server).cmdUpdate},
+		"rescan":      {0, 1, (*Server).cmdUpdate},
+
+		// Stickers
+		"sticker": {3, -1, (*Server).cmdSticker},
+
+		// Outputs
+		"outputs":       {0, 0, (*Server).cmdOutputs},
+		"enableoutput":  {1, 1, (*Server).cmdEnableOutput},
+		"disableoutput": {1, 1, (*Server).cmdDisableOutput},
+		"toggleoutput":  {1, 1, (*Server).cmdToggleOutput},
+		"outputset":     {3, 3, (*Server).cmdOutputSet},
+	}
+}
+
+// tagTypes are the tags the server claims to support.
+var tagTypes = []string{
+	"Artist", "ArtistSort", "Album", "AlbumSort", "AlbumArtist",
+	"AlbumArtistSort", "Title", "Track", "Name", "Genre", "Date",
+	"OriginalDate", "Composer", "Performer", "Conductor", "Work",
+	"Grouping", "Comment", "Disc", "Label", "MUSICBRAINZ_ARTISTID",
+	"MUSICBRAINZ_ALBUMID", "MUSICBRAINZ_ALBUMARTISTID",
+	"MUSICBRAINZ_TRACKID", "MUSICBRAINZ_RELEASETRACKID",
+	"MUSICBRAINZ_WORKID",
+}
+
+func cmdNop(s *Server, r *Response, args []string) error {
+	return nil
+}
+
+func cmdCommands(s *Server, r *Response, args []string) error {
+	names := []string{"close", "idle", "noidle", "password"}
+	for name := range commands {
+		names = append(names, name)
+	}
+	for name := range connCommands {
+		names = append(names, name)
+	}
+	for name := range s.handlers {
+		_, builtin := connCommands[name]
+		if _, ok := commands[name]; !ok && !builtin {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		r.Add("command", name)
+	}
+	return nil
+}
+
+func cmdTagTypes(s *Server, r *Response, args []string) error {
+	if len(args) == 0 {
+		for _, name := range tagTypes {
+			r.Add("tagtype", name)
+		}
+	}
+	return nil
+}
+
+func cmdURLHandlers(s *Server, r *Response, args []string) error {
+	r.Add("handler", "http://")
+	r.Add("handler", "https://")
+	return nil
+}
+
+// Argument parsing
+
+func parseInt(v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, Errorf(AckArg, "Integer expected: %s", v)
+	}
+	return n, nil
+}
+
+func parseBool(v string) (bool, error) {
+	switch v {
+	case "0":
+		return false, nil
+	case "1":
+		return true, nil
+	}
+	return false, Errorf(AckArg, "Boolean (0/1) expected: %s", v)
+}
+
+func parseFloat(v string) (float64, error) {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, Errorf(AckArg, "Float expected: %s", v)
+	}
+	return f, nil
+}
+
+// parseRange parses a position or a START:END range. An open END stands
+// for max.
+func parseRange(v string, max int) (start, end int, err error) {
+	if i := strings.Index(v, ":"); i > -1 {
+		if start, err = parseInt(v[:i]); err != nil {
+			return
+		}
+
+		end = max
+		if i+1 < len(v) {
+			if end, err = parseInt(v[i+1:]); err != nil {
+				return
+			}
+		}
+	} else {
+		if start, err = parseInt(v); err != nil {
+			return
+		}
+		end = start + 1
+	}
+
+	if start < 0 || end > max || start > end {
+		err = Errorf(AckArg, "Bad song index")
+	}
+	return
+}
+
+// Status
+
+func (s *Server) cmdStatus(r *Response, args []string) error {
+	p := &s.player
+
+	r.Addf("volume", "%d", p.volume)
+	r.Add("repeat", boolString(p.repeat))
+	r.Add("random", boolString(p.random))
+	r.Add("single", p.singleString())
+	r.Add("consume", boolString(p.consume))
+	r.Addf("playlist", "%d", s.plVersion)
+	r.Addf("playlistlength", "%d", len(s.queue))
+	r.Add("mixrampdb", "0.000000")
+	r.Add("state", p.state)
+	r.Add("partition", s.partition)
+
+	if p.xfade > 0 {
+		r.Addf("xfade", "%d", p.xfade)
+	}
+
+	if q := s.current(); q != nil {
+		r.Addf("song", "%d", p.song)
+		r.Addf("songid", "%d", q.id)
+
+		if p.state != "stop" {
+			var duration float64
+			if v := q.song.tagValues("duration"); len(v) > 0 {
+				duration, _ = strconv.ParseFloat(v[0], 64)
+			}
+
+			r.Addf("time", "%d:%d", int(p.elapsed), int(duration+0.5))
+			r.Addf("elapsed", "%.3f", p.elapsed)
+			if duration > 0 {
+				r.Addf("duration", "%.3f", duration)
+			}
+			r.Add("bitrate", "0")
+		}
+
+		if next := p.song + 1; next < len(s.queue) {
+			r.Addf("nextsong", "%d", next)
+			r.Addf("nextsongid", "%d", s.queue[next].id)
+		}
+	}
+
+	if s.updateId > 0 {
+		r.Addf("updating_db", "%d", s.updateId)
+	}
+	return nil
+}
+
+func (s *Server) cmdStats(r *Response, args []string) error {
+	artists := make(map[string]bool)
+	albums := make(map[string]bool)
+
+	for _, song := range s.db {
+		for _, v := range song.tagValues("Artist") {
+			artists[v] = true
+		}
+		for _, v := range song.tagValues("Album") {
+			albums[v] = true
+		}
+	}
+
+	r.Addf("uptime", "%d", 1)
+	r.Addf("playtime", "%d", 0)
+	r.Addf("artists", "%d", len(artists))
+	r.Addf("albums", "%d", len(albums))
+	r.Addf("songs", "%d", len(s.db))
+	r.Addf("db_playtime", "%d", 0)
+	r.Addf("db_update", "%d", 1325376000)
+	return nil
+}
+
+func (s *Server) cmdCurrentSong(r *Response, args []string) error {
+	if q := s.current(); q != nil {
+		writeQueued(r, q, s.player.song)
+	}
+	return nil
+}
+
+// Playback options and control
+
+func boolString(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+func (p *player) singleString() string {
+	if len(p.single) == 0 {
+		return "0"
+	}
+	return p.single
+}
+
+func (s *Server) setOption(v *bool, arg string) error {
+	b, err := parseBool(arg)
+	if err != nil {
+		return err
+	}
+
+	*v = b
+	s.notify("options")
+	return nil
+}
+
+func (s *Server) cmdConsume(r *Response, args []string) error {
+	return s.setOption(&s.player.consume, args[0])
+}
+
+func (s *Server) cmdRandom(r *Response, args []string) error {
+	return s.setOption(&s.player.random, args[0])
+}
+
+func (s *Server) cmdRepeat(r *Response, args []string) error {
+	return s.setOption(&s.player.repeat, args[0])
+}
+
+func (s *Server) cmdSingle(r *Response, args []string) error {
+	switch args[0] {
+	case "0", "1", "oneshot":
+		s.player.single = args[0]
+	default:
+		return Errorf(AckArg, "Boolean (0/1) or \"oneshot\" expected: %s", args[0])
+	}
+
+	s.notify("options")
+	return nil
+}
+
+func (s *Server) cmdCrossfade(r *Response, args []string) error {
+	n, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	s.player.xfade = n
+	s.notify("options")
+	return nil
+}
+
+func (s *Server) cmdSetVol(r *Response, args []string) error {
+	n, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	if n < 0 || n > 100 {
+		return Errorf(AckArg, "Invalid volume value")
+	}
+
+	s.player.volume = n
+	s.notify("mixer")
+	return nil
+}
+
+// playAt starts playing the song at pos.
+func (s *Server) playAt(pos int) error {
+	if pos < 0 || pos >= len(s.queue) {
+		return Errorf(AckArg, "Bad song index")
+	}
+
+	s.player.song = pos
+	s.player.state = "play"
+	s.player.elapsed = 0
+	s.notify("player")
+	return nil
+}
+
+func (s *Server) cmdPlay(r *Response, args []string) error {
+	if len(args) == 0 {
+		if s.player.state == "pause" {
+			s.player.state = "play"
+			s.notify("player")
+			return nil
+		}
+
+		if s.current() != nil {
+			return s.playAt(s.player.song)
+		}
+		return s.playAt(0)
+	}
+
+	pos, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+	return s.playAt(pos)
+}
+
+func (s *Server) cmdPlayId(r *Response, args []string) error {
+	if len(args) == 0 {
+		return s.cmdPlay(r, nil)
+	}
+
+	id, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	pos := s.position(id)
+	if pos == -1 {
+		return Errorf(AckNoExist, "No such song")
+	}
+	return s.playAt(pos)
+}
+
+func (s *Server) cmdPause(r *Response, args []string) error {
+	pause := s.player.state == "play"
+
+	if len(args) > 0 {
+		var err error
+		if pause, err = parseBool(args[0]); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case s.player.state == "stop":
+	case pause:
+		s.player.state = "pause"
+	default:
+		s.player.state = "play"
+	}
+
+	s.notify("player")
+	return nil
+}
+
+func (s *Server) cmdStop(r *Response, args []string) error {
+	s.player.state = "stop"
+	s.player.elapsed = 0
+	s.notify("player")
+	return nil
+}
+
+func (s *Server) cmdNext(r *Response, args []string) error {
+	if s.player.state == "stop" {
+		return nil
+	}
+
+	if next := s.player.song + 1; next < len(s.queue) {
+		return s.playAt(next)
+	}
+	return s.cmdStop(r, nil)
+}
+
+func (s *Server) cmdPrevious(r *Response, args []string) error {
+	if s.player.state == "stop" {
+		return nil
+	}
+
+	if prev := s.player.song - 1; prev >= 0 {
+		return s.playAt(prev)
+	}
+	return s.playAt(s.player.song)
+}
+
+func (s *Server) seek(pos int, arg string) error {
+	t, err := parseFloat(arg)
+	if err != nil {
+		return err
+	}
+
+	if err = s.playAt(pos); err != nil {
+		return err
+	}
+
+	s.player.elapsed = t
+	return nil
+}
+
+func (s *Server) cmdSeek(r *Response, args []string) error {
+	pos, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+	return s.seek(pos, args[1])
+}
+
+func (s *Server) cmdSeekId(r *Response, args []string) error {
+	id, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	pos := s.position(id)
+	if pos == -1 {
+		return Errorf(AckNoExist, "No such song")
+	}
+	return s.seek(pos, args[1])
+}
+
+func (s *Server) cmdSeekCur(r *Response, args []string) error {
+	if s.current() == nil || s.player.state == "stop" {
+		return Errorf(AckPlayerSync, "Not playing")
+	}
+
+	t, err := parseFloat(args[0])
+	if err != nil {
+		return err
+	}
+
+	s.player.elapsed = t
+	s.notify("player")
+	return nil
+}
+
+// Queue
+
+// insert adds songs to the queue at pos, or at the end if pos is -1, and
+// returns the id of the first new entry.
+func (s *Server) insert(songs []*Song, pos int) int {
+	if pos < 0 || pos > len(s.queue) {
+		pos = len(s.queue)
+	}
+
+	first := s.nextId
+	list := make([]*queued, len(songs))
+	for i, song := range songs {
+		list[i] = &queued{song: song, id: s.nextId}
+		s.nextId++
+	}
+
+	s.queue = append(s.queue[:pos:pos], append(list, s.queue[pos:]...)...)
+
+	if s.player.song >= pos && s.current() != nil {
+		s.player.song += len(songs)
+	}
+
+	s.queueChanged()
+	return first
+}
+
+// insertPosition parses the optional position argument of add and addid.
+// Positions may be relative to the current song when prefixed by + or -.
+func (s *Server) insertPosition(args []string) (int, error) {
+	if len(args) < 2 {
+		return -1, nil
+	}
+
+	v, base := args[1], 0
+	if strings.HasPrefix(v, "+") || strings.HasPrefix(v, "-") {
+		if s.current() == nil {
+			return 0, Errorf(AckPlayerSync, "No current song")
+		}
+		base = s.player.song + 1
+		if v[0] == '-' {
+			base = s.player.song
+		}
+		v = v[1:]
+	}
+
+	n, err := parseInt(v)
+	if err != nil {
+		return 0, err
+	}
+
+	if pos := base + n; pos <= len(s.queue) {
+		return pos, nil
+	}
+	return 0, Errorf(AckArg, "Bad song index")
+}
+
+func (s *Server) lookup(uri string) ([]*Song, error) {
+	if song, ok := s.db[uri]; ok {
+		return []*Song{song}, nil
+	}
+
+	if list := s.songs(uri); len(list) > 0 && s.isDirectory(uri) {
+		return list, nil
+	}
+
+	return nil, Errorf(AckNoExist, "No such directory")
+}
+
+func (s *Server) cmdAdd(r *Response, args []string) error {
+	songs, err := s.lookup(args[0])
+	if err != nil {
+		return err
+	}
+
+	pos, err := s.insertPosition(args)
+	if err != nil {
+		return err
+	}
+
+	s.insert(songs, pos)
+	return nil
+}
+
+func (s *Server) cmdAddId(r *Response, args []string) error {
+	song, ok := s.db[args[0]]
+	if !ok {
+		return Errorf(AckNoExist, "No such song")
+	}
+
+	pos, err := s.insertPosition(args)
+	if err != nil {
+		return err
+	}
+
+	r.Addf("Id", "%d", s.insert([]*Song{song}, pos))
+	return nil
+}
+
+func (s *Server) cmdClear(r *Response, args []string) error {
+	s.queue = nil
+	s.player.song = -1
+	s.player.state = "stop"
+	s.queueChanged()
+	return nil
+}
+
+// remove deletes the songs in [start, end) from the queue.
+func (s *Server) remove(start, end int) {
+	s.queue = append(s.queue[:start:start], s.queue[end:]...)
+
+	switch p := &s.player; {
+	case p.song >= end:
+		p.song -= end - start
+	case p.song >= start:
+		p.song = -1
+		p.state = "stop"
+		s.notify("player")
+	}
+
+	s.queueChanged()
+}
+
+func (s *Server) cmdDelete(r *Response, args []string) error {
+	start, end, err := parseRange(args[0], len(s.queue))
+	if err != nil {
+		return err
+	}
+
+	if start == end {
+		return Errorf(AckArg, "Bad song index")
+	}
+
+	s.remove(start, end)
+	return nil
+}
+
+func (s *Server) cmdDeleteId(r *Response, args []string) error {
+	id, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	pos := s.position(id)
+	if pos == -1 {
+		return Errorf(AckNoExist, "No such song")
+	}
+
+	s.remove(pos, pos+1)
+	return nil
+}
+
+// move moves the song at src to dst.
+func (s *Server) move(src, dst int) error {
+	if src < 0 || src >= len(s.queue) || dst < 0 || dst >= len(s.queue) {
+		return Errorf(AckArg, "Bad song index")
+	}
+
+	var current *queued
+	if s.current() != nil {
+		current = s.queue[s.player.song]
+	}
+
+	q := s.queue[src]
+	s.queue = append(s.queue[:src], s.queue[src+1:]...)
+	s.queue = append(s.queue[:dst], append([]*queued{q}, s.queue[dst:]...)...)
+
+	if current != nil {
+		s.player.song = s.position(current.id)
+	}
+
+	s.queueChanged()
+	return nil
+}
+
+func (s *Server) cmdMove(r *Response, args []string) error {
+	start, end, err := parseRange(args[0], len(s.queue))
+	if err != nil {
+		return err
+	}
+
+	dst, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < end-start; i++ {
+		if err = s.move(start+i, dst+i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) cmdMoveId(r *Response, args []string) error {
+	id, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	dst, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	pos := s.position(id)
+	if pos == -1 {
+		return Errorf(AckNoExist, "No such song")
+	}
+	return s.move(pos, dst)
+}
+
+func (s *Server) swap(a, b int) error {
+	if a < 0 || a >= len(s.queue) || b < 0 || b >= len(s.queue) {
+		return Errorf(AckArg, "Bad song index")
+	}
+
+	s.queue[a], s.queue[b] = s.queue[b], s.queue[a]
+
+	switch s.player.song {
+	case a:
+		s.player.song = b
+	case b:
+		s.player.song = a
+	}
+
+	s.queueChanged()
+	return nil
+}
+
+func (s *Server) cmdSwap(r *Response, args []string) error {
+	a, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	b, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+	return s.swap(a, b)
+}
+
+func (s *Server) cmdSwapId(r *Response, args []string) error {
+	a, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	b, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	pa, pb := s.position(a), s.position(b)
+	if pa == -1 || pb == -1 {
+		return Errorf(AckNoExist, "No such song")
+	}
+	return s.swap(pa, pb)
+}
+
+// cmdShuffle reverses the queue, which is predictable enough for tests.
+func (s *Server) cmdShuffle(r *Response, args []string) error {
+	for i, j := 0, len(s.queue)-1; i < j; i, j = i+1, j-1 {
+		s.swap(i, j)
+	}
+
+	s.queueChanged()
+	return nil
+}
+
+func (s *Server) cmdPlaylistInfo(r *Response, args []string) error {
+	start, end := 0, len(s.queue)
+
+	if len(args) > 0 {
+		var err error
+		if start, end, err = parseRange(args[0], len(s.queue)); err != nil {
+			return err
+		}
+	}
+
+	for i := start; i < end; i++ {
+		writeQueued(r, s.queue[i], i)
+	}
+	return nil
+}
+
+func (s *Server) cmdPlaylistId(r *Response, args []string) error {
+	if len(args) == 0 {
+		return s.cmdPlaylistInfo(r, nil)
+	}
+
+	id, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	pos := s.position(id)
+	if pos == -1 {
+		return Errorf(AckNoExist, "No such song")
+	}
+
+	writeQueued(r, s.queue[pos], pos)
+	return nil
+}
+
+// cmdPlChanges reports the whole queue for any version other than the
+// current one. That is correct, if not minimal.
+func (s *Server) cmdPlChanges(r *Response, args []string) error {
+	version, err := parseInt(args[0])
+	if err != nil {
+		return err
+	}
+
+	if version != s.plVersion {
+		return s.cmdPlaylistInfo(r, args[1:])
+	}
+	return nil
+}
+
+func (s *Server) playlistMatch(r *Response, args []string, exact bool) error {
+	args, opts, err := splitQuery(args)
+	if err != nil {
+		return err
+	}
+
+	match, err := parseMatch(args, exact)
+	if err != nil {
+		return err
+	}
+
+	var found []int
+	for i, q := range s.queue {
+		if match(q.song) {
+			found = append(found, i)
+		}
+	}
+
+	song := func(i int) *Song { return s.queue[found[i]].song }
+	for _, i := range opts.order(len(found), song) {
+		writeQueued(r, s.queue[found[i]], found[i])
+	}
+	return nil
+}
+
+func (s *Server) cmdPlaylistFind(r *Response, args []string) error {
+	return s.playlistMatch(r, args, true)
+}
+
+func (s *Server) cmdPlaylistSearch(r *Response, args []string) error {
+	return s.playlistMatch(r, args, false)
+}
+
+// Stored playlists
+
+func (s *Server) playlist(name string) ([]string, error) {
+	files, ok := s.playlists[name]
+	if !ok {
+		return nil, Errorf(AckNoExist, "No such playlist")
+	}
+	return files, nil
+}
+
+func (s *Server) cmdListPlaylists(r *Response, args []string) error {
+	var names []string
+	for name := range s.playlists {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		r.Add("playlist", name)
+		r.Add("Last-Modified", lastModified)
+	}
+	return nil
+}
+
+func (s *Server) cmdListPlaylist(r *Response, args []string) error {
+	files, err := s.playlist(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		r.Add("file", file)
+	}
+	return nil
+}
+
+func (s *Server) cmdListPlaylistInfo(r *Response, args []string) error {
+	files, err := s.playlist(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if song, ok := s.db[file]; ok {
+			writeSong(r, song)
+		} else {
+			r.Add("file", file)
+		}
+	}
+	return nil
+}
+
+func (s *Server) cmdLoad(r *Response, args []string) error {
+	files, err := s.playlist(args[0])
+	if err != nil {
+		return err
+	}
+
+	start, end := 0, len(files)
+	if len(args) > 1 {
+		if start, end, err = parseRange(args[1], len(files)); err != nil {
+			return err
+		}
+	}
+
+	var songs []*Song
+	for _, file := range files[start:end] {
+		song, ok := s.db[file]
+		if !ok {
+			song = &Song{File: file}
+		}
+		songs = append(songs, song)
+	}
+
+	s.insert(songs, -1)
+	return nil
+}
+
+func (s *Server) cmdSave(r *Response, args []string) error {
+	if _, ok := s.playlists[args[0]]; ok {
+		return Errorf(AckExist, "Playlist already exists")
+	}
+
+	var files []string
+	for _, q := range s.queue {
+		files = append(files, q.song.File)
+	}
+
+	s.playlists[args[0]] = files
+	s.notify("stored_playlist")
+	return nil
+}
+
+func (s *Server) cmdRm(r *Response, args []string) error {
+	if _, err := s.playlist(args[0]); err != nil {
+		return err
+	}
+
+	delete(s.playlists, args[0])
+	s.notify("stored_playlist")
+	return nil
+}
+
+func (s *Server) cmdRename(r *Response, args []string) error {
+	files, err := s.playlist(args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, ok := s.playlists[args[1]]; ok {
+		return Errorf(AckExist, "Playlist already exists")
+	}
+
+	delete(s.playlists, args[0])
+	s.playlists[args[1]] = files
+	s.notify("stored_playlist")
+	return nil
+}
+
+func (s *Server) cmdPlaylistAdd(r *Response, args []string) error {
+	songs, err := s.lookup(args[1])
+	if err != nil {
+		return err
+	}
+
+	for _, song := range songs {
+		s.playlists[args[0]] = append(s.playlists[args[0]], song.File)
+	}
+
+	s.notify("stored_playlist")
+	return nil
+}
+
+func (s *Server) cmdPlaylistClear(r *Response, args []string) error {
+	s.playlists[args[0]] = nil
+	s.notify("stored_playlist")
+	return nil
+}
+
+func (s *Server) cmdPlaylistDelete(r *Response, args []string) error {
+	files, err := s.playlist(args[0])
+	if err != nil {
+		return err
+	}
+
+	start, end, err := parseRange(args[1], len(files))
+	if err != nil {
+		return err
+	}
+
+	s.playlists[args[0]] = append(files[:start:start], files[end:]...)
+	s.notify("stored_playlist")
+	return nil
+}
+
+func (s *Server) cmdPlaylistMove(r *Response, args []string) error {
+	files, err := s.playlist(args[0])
+	if err != nil {
+		return err
+	}
+
+	src, err := parseInt(args[1])
+	if err != nil {
+		return err
+	}
+
+	dst, err := parseInt(args[2])
+	if err != nil {
+		return err
+	}
+
+	if src < 0 || src >= len(files) || dst < 0 || dst >= len(files) {
+		return Errorf(AckArg, "Bad song index")
+	}
+
+	file := files[src]
+	files = append(files[:src], files[src+1:]...)
+	files = append(files[:dst], append([]string{file}, files[dst:]...)...)
+
+	s.playlists[args[0]] = files
+	s.notify("stored_playlist")
+	return nil
+}
+
+// Database
+
+// parseMatch parses a filter expression or TAG TERM pairs into a function
+// matching songs. Exact matches are case-sensitive; other matches ignore
+// case, and TAG TERM pairs look for substrings.
+func parseMatch(args []string, exact bool) (func(*Song) bool, error) {
+	if len(args) == 1 && strings.HasPrefix(args[0], "(") {
+		return parseFilter(args[0], !exact)
+	}
+
+	if len(args)%2 != 0 {
+		return nil, Errorf(AckArg, "Incorrect number of filter arguments")
+	}
+
+	type term struct{ tag, value string }
+	var terms []term
+
+	for i := 0; i < len(args); i += 2 {
+		terms = append(terms, term{args[i], args[i+1]})
+	}
+
+	equal := func(a, b string) bool {
+		if exact {
+			return a == b
+		}
+		return strings.Contains(strings.ToLower(a), strings.ToLower(b))
+	}
+
+	return func(song *Song) bool {
+		for _, t := range terms {
+			var values []string
+
+			switch strings.ToLower(t.tag) {
+			case "any":
+				values = append(values, song.File)
+				for _, tag := range song.Tags {
+					values = append(values, tag.Value)
+				}
+			case "file":
+				values = []string{song.File}
+			case "base":
+				if !strings.HasPrefix(song.File, strings.Trim(t.value, "/")+"/") {
+					return false
+				}
+				continue
+			default:
+				values = song.tagValues(t.tag)
+			}
+
+			found := false
+			for _, v := range values {
+				if equal(v, t.value) {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// match returns the songs in the database matching args, which may end in
+// sort and window options.
+func (s *Server) match(args []string, exact bool) ([]*Song, error) {
+	list, _, err := s.matchQuery(args, exact)
+	return list, err
+}
+
+// matchQuery is like match, but also returns the options following the
+// filter.
+func (s *Server) matchQuery(args []string, exact bool) ([]*Song, query, error) {
+	args, opts, err := splitQuery(args)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	match, err := parseMatch(args, exact)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	var found []*Song
+	for _, song := range s.allSongs() {
+		if match(song) {
+			found = append(found, song)
+		}
+	}
+
+	list := make([]*Song, 0, len(found))
+	for _, i := range opts.order(len(found), func(i int) *Song { return found[i] }) {
+		list = append(list, found[i])
+	}
+	return list, opts, nil
+}
+
+func (s *Server) cmdFind(r *Response, args []string) error {
+	list, err := s.match(args, true)
+	if err != nil {
+		return err
+	}
+
+	for _, song := range list {
+		writeSong(r, song)
+	}
+	return nil
+}
+
+func (s *Server) cmdSearch(r *Response, args []string) error {
+	list, err := s.match(args, false)
+	if err != nil {
+		return err
+	}
+
+	for _, song := range list {
+		writeSong(r, song)
+	}
+	return nil
+}
+
+// matchAdd adds the songs matching args to the queue, at the position given
+// in the options, if any.
+func (s *Server) matchAdd(args []string, exact bool) error {
+	list, opts, err := s.matchQuery(args, exact)
+	if err != nil {
+		return err
+	}
+
+	pos := -1
+	if len(opts.position) > 0 {
+		if pos, err = s.insertPosition([]string{"", opts.position}); err != nil {
+			return err
+		}
+	}
+
+	s.insert(list, pos)
+	return nil
+}
+
+func (s *Server) cmdFindAdd(r *Response, args []string) error {
+	return s.matchAdd(args, true)
+}
+
+func (s *Server) cmdSearchAdd(r *Response, args []string) error {
+	return s.matchAdd(args, false)
+}
+
+func (s *Server) cmdSearchAddPl(r *Response, args []string) error {
+	list, opts, err := s.matchQuery(args[1:], false)
+	if err != nil {
+		return err
+	}
+
+	files := s.playlists[args[0]]
+	pos := len(files)
+	if len(opts.position) > 0 {
+		if pos, err = parseInt(opts.position); err != nil {
+			return err
+		} else if pos < 0 || pos > len(files) {
+			return Errorf(AckArg, "Bad position")
+		}
+	}
+
+	var added []string
+	for _, song := range list {
+		added = append(added, song.File)
+	}
+
+	s.playlists[args[0]] = append(files[:pos:pos], append(added, files[pos:]...)...)
+	s.notify("stored_playlist")
+	return nil
+}
+
+func (s *Server) cmdCount(r *Response, args []string) error {
+	args, groups := splitGroups(args)
+	if len(groups) > 1 {
+		return Errorf(AckArg, "Too many group arguments")
+	}
+
+	list, err := s.match(args, true)
+	if err != nil {
+		return err
+	}
+
+	if len(groups) == 0 {
+		writeCount(r, list)
+		return nil
+	}
+
+	name := tagName(groups[0])
+	byValue := make(map[string][]*Song)
+	for _, song := range list {
+		v, _ := song.tag(name)
+		byValue[v] = append(byValue[v], song)
+	}
+
+	values := make([]string, 0, len(byValue))
+	for v := range byValue {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	for _, v := range values {
+		r.Add(name, v)
+		writeCount(r, byValue[v])
+	}
+	return nil
+}
+
+// writeCount writes the number of songs in list and their total playtime in
+// whole seconds.
+func writeCount(r *Response, list []*Song) {
+	var playtime float64
+	for _, song := range list {
+		if v, ok := song.tag("duration"); ok {
+			d, _ := strconv.ParseFloat(v, 64)
+			playtime += d
+		}
+	}
+
+	r.Addf("songs", "%d", len(list))
+	r.Addf("playtime", "%d", int(playtime+0.5))
+}
+
+func (s *Server) cmdList(r *Response, args []string) error {
+	args, groups := splitGroups(args)
+
+	list, err := s.match(args[1:], true)
+	if err != nil {
+		return err
+	}
+
+	// The first group is the outermost one; the listed tag comes last.
+	tags := append(groups, args[0])
+	for i, tag := range tags {
+		tags[i] = tagName(tag)
+	}
+
+	writeTree(r, tags, list)
+	return nil
+}
+
+// writeTree writes the distinct values of tags[0] among the songs in list,
+// each followed by the tree of the remaining tags of its songs.
+func writeTree(r *Response, tags []string, list []*Song) {
+	byValue := make(map[string][]*Song)
+	for _, song := range list {
+		v := song.tagValues(tags[0])
+		if strings.EqualFold(tags[0], "file") {
+			v = []string{song.File}
+		} else if len(v) == 0 && len(tags) > 1 {
+			// Songs without a group tag are listed under an empty value.
+			v = []string{""}
+		}
+
+		for _, value := range v {
+			byValue[value] = append(byValue[value], song)
+		}
+	}
+
+	values := make([]string, 0, len(byValue))
+	for v := range byValue {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	for _, v := range values {
+		r.Add(tags[0], v)
+		if len(tags) > 1 {
+			writeTree(r, tags[1:], byValue[v])
+		}
+	}
+}
+
+// splitGroups removes the trailing 'group TAG' arguments from args. The
+// groups are returned in the order they were given.
+func splitGroups(args []string) ([]string, []string) {
+	var groups []string
+	for len(args) >= 2 && args[len(args)-2] == "group" {
+		groups = append([]string{args[len(args)-1]}, groups...)
+		args = args[:len(args)-2]
+	}
+	return args, groups
+}
+
+// tagName returns the canonical spelling of a tag name.
+func tagName(name string) string {
+	for _, v := range tagTypes {
+		if strings.EqualFold(v, name) {
+			return v
+		}
+	}
+	return name
+}
+
+// listDirectory writes the contents of the directory at uri. With
+// recursive set, the contents of all subdirectories follow their entry.
+func (s *Server) listDirectory(r *Response, uri string, recursive, info bool) error {
+	if !s.isDirectory(uri) {
+		if song, ok := s.db[uri]; ok {
+			if info {
+				writeSong(r, song)
+			} else {
+				r.Add("file", song.File)
+			}
+			return nil
+		}
+		return Errorf(AckNoExist, "Not found")
+	}
+
+	uri = strings.Trim(uri, "/")
+
+	for _, dir := range s.directories(uri) {
+		r.Add("directory", dir)
+		if info {
+			r.Add("Last-Modified", lastModified)
+		}
+
+		if recursive {
+			s.listDirectory(r, dir, true, info)
+		}
+	}
+
+	for _, song := range s.songs(uri) {
+		parent := ""
+		if i := strings.LastIndex(song.File, "/"); i > -1 {
+			parent = song.File[:i]
+		}
+
+		if parent != uri {
+			continue
+		}
+
+		if info {
+			writeSong(r, song)
+		} else {
+			r.Add("file", song.File)
+		}
+	}
+	return nil
+}
+
+func optionalArg(args []string) string {
+	if len(args) > 0 {
+		return args[0]
+	}
+	return ""
+}
+
+func (s *Server) cmdListAll(r *Response, args []string) error {
+	return s.listDirectory(r, optionalArg(args), true, false)
+}
+
+func (s *Server) cmdListAllInfo(r *Response, args []string) error {
+	return s.listDirectory(r, optionalArg(args), true, true)
+}
+
+func (s *Server) cmdLsInfo(r *Response, args []string) error {
+	if err := s.listDirectory(r, optionalArg(args), false, true); err != nil {
+		return err
+	}
+
+	if len(args) == 0 || len(strings.Trim(args[0], "/")) == 0 {
+		s.cmdListPlaylists(r, nil)
+	}
+	return nil
+}
+
+// cmdUpdate finishes the update right away, but reports its job id.
+func (s *Server) cmdUpdate(r *Response, args []string) error {
+	s.updateId++
+	r.Addf("updating_db", "%d", s.updateId)
+	s.notify("update", "database")
+	return nil
+}
+
+// Outputs
+
+func (s *Server) cmdOutputs(r *Response, args []string) error {
+	for _, o := range s.outputs {
+		if o.partition != s.partition {
+			continue
+		}
+
+		r.Addf("outputid", "%d", o.id)
+		r.Add("outputname", o.name)
+		r.Add("plugin", o.plugin)
+		r.Add("outputenabled", boolString(o.enabled))
+
+		names := make([]string, 0, len(o.attributes))
+		for name := range o.attributes {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			r.Addf("attribute", "%s=%s", name, o.attributes[name])
+		}
+	}
+	return nil
+}
+
+func (s *Server) output(arg string) (*output, error) {
+	id, err := parseInt(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	if id < 0 || id >= len(s.outputs) || s.outputs[id].partition != s.partition {
+		return nil, Errorf(AckNoExist, "No such audio output")
+	}
+	return s.outputs[id], nil
+}
+
+func (s *Server) setOutput(arg string, enabled func(bool) bool) error {
+	o, err := s.output(arg)
+	if err != nil {
+		return err
+	}
+
+	o.enabled = enabled(o.enabled)
+	s.notify("output")
+	return nil
+}
+
+func (s *Server) cmdEnableOutput(r *Response, args []string) error {
+	return s.setOutput(args[0], func(bool) bool { return true })
+}
+
+func (s *Server) cmdDisableOutput(r *Response, args []string) error {
+	return s.setOutput(args[0], func(bool) bool { return false })
+}
+
+func (s *Server) cmdToggleOutput(r *Response, args []string) error {
+	return s.setOutput(args[0], func(v bool) bool { return !v })
+}
+
+func (s *Server) cmdOutputSet(r *Response, args []string) error {
+	o, err := s.output(args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, ok := o.attributes[args[1]]; !ok {
+		return Errorf(AckArg, "Unsupported attribute %q", args[1])
+	}
+
+	o.attributes[args[1]] = args[2]
+	s.notify("output")
+	return nil
+}
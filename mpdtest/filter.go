@@ -0,0 +1,307 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpdtest
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// matcher reports whether a song matches a filter.
+type matcher func(*Song) bool
+
+// filterParser parses filter expressions such as
+// "((artist == 'Tool') AND (!(title contains 'live')))".
+type filterParser struct {
+	s    string
+	pos  int
+	fold bool // Compare tag values without regard to case.
+}
+
+// parseFilter parses a filter expression. With fold set, tag values are
+// compared without regard to case, as the search commands do.
+func parseFilter(expr string, fold bool) (matcher, error) {
+	p := &filterParser{s: expr, fold: fold}
+
+	m, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, p.errorf("Unparsed garbage after expression")
+	}
+	return m, nil
+}
+
+func (p *filterParser) errorf(format string, arg ...interface{}) error {
+	return Errorf(AckArg, format, arg...)
+}
+
+func (p *filterParser) skipSpace() {
+	for p.pos < len(p.s) && p.s[p.pos] == ' ' {
+		p.pos++
+	}
+}
+
+// consume skips s if the input continues with it.
+func (p *filterParser) consume(s string) bool {
+	if p.skipSpace(); strings.HasPrefix(p.s[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+// word reads a run of characters up to the next space or parenthesis.
+func (p *filterParser) word() string {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && !strings.ContainsRune(" ()'\"", rune(p.s[p.pos])) {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+// value reads a quoted string. Backslashes escape the next character.
+func (p *filterParser) value() (string, error) {
+	p.skipSpace()
+	if p.pos >= len(p.s) || (p.s[p.pos] != '\'' && p.s[p.pos] != '"') {
+		return "", p.errorf("Quoted string expected")
+	}
+
+	quote := p.s[p.pos]
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		p.pos++
+
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.s):
+			b.WriteByte(p.s[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", p.errorf("Closing quote not found")
+}
+
+func (p *filterParser) expr() (matcher, error) {
+	if !p.consume("(") {
+		return nil, p.errorf("'(' expected")
+	}
+
+	m, err := p.inner()
+	if err != nil {
+		return nil, err
+	}
+
+	if !p.consume(")") {
+		return nil, p.errorf("')' expected")
+	}
+	return m, nil
+}
+
+// inner parses the part of an expression between its parentheses.
+func (p *filterParser) inner() (matcher, error) {
+	p.skipSpace()
+
+	if p.consume("!") {
+		m, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return func(s *Song) bool { return !m(s) }, nil
+	}
+
+	if p.pos < len(p.s) && p.s[p.pos] == '(' {
+		var all []matcher
+		for {
+			m, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+
+			all = append(all, m)
+			if !p.consume("AND") {
+				break
+			}
+		}
+
+		return func(s *Song) bool {
+			for _, m := range all {
+				if !m(s) {
+					return false
+				}
+			}
+			return true
+		}, nil
+	}
+
+	tag := p.word()
+	switch tag {
+	case "":
+		return nil, p.errorf("Word expected")
+
+	case "base":
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		base := strings.Trim(v, "/") + "/"
+		return func(s *Song) bool { return strings.HasPrefix(s.File, base) }, nil
+
+	case "modified-since":
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+
+		since, err := parseTime(v)
+		if err != nil {
+			return nil, p.errorf("Failed to parse time stamp")
+		}
+
+		modified, _ := time.Parse(time.RFC3339, lastModified)
+		return func(s *Song) bool { return !modified.Before(since) }, nil
+
+	case "prio":
+		if !p.consume(">=") {
+			return nil, p.errorf("'>=' expected")
+		}
+
+		n, err := strconv.Atoi(p.word())
+		if err != nil {
+			return nil, p.errorf("Number expected")
+		}
+
+		return func(s *Song) bool {
+			prio, _ := s.tag("Prio")
+			v, _ := strconv.Atoi(prio)
+			return v >= n
+		}, nil
+	}
+
+	op := p.word()
+	v, err := p.value()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(tag, "AudioFormat") {
+		return p.audioFormat(op, v)
+	}
+
+	cmp, err := p.compare(op, v)
+	if err != nil {
+		return nil, err
+	}
+
+	negate := op == "!=" || op == "!~"
+	return func(s *Song) bool {
+		for _, value := range tagValues(s, tag) {
+			if cmp(value) {
+				return !negate
+			}
+		}
+		return negate
+	}, nil
+}
+
+// compare returns a function comparing a tag value with v.
+func (p *filterParser) compare(op, v string) (func(string) bool, error) {
+	fold := func(s string) string { return s }
+	if p.fold {
+		fold = strings.ToLower
+		v = fold(v)
+	}
+
+	switch op {
+	case "==", "!=":
+		return func(s string) bool { return fold(s) == v }, nil
+	case "contains":
+		return func(s string) bool { return strings.Contains(fold(s), v) }, nil
+	case "starts_with":
+		return func(s string) bool { return strings.HasPrefix(fold(s), v) }, nil
+	case "=~", "!~":
+		if p.fold {
+			v = "(?i)" + v
+		}
+
+		re, err := regexp.Compile(v)
+		if err != nil {
+			return nil, p.errorf("Failed to compile regex")
+		}
+		return re.MatchString, nil
+	}
+
+	return nil, p.errorf("Unknown filter operator: %s", op)
+}
+
+// audioFormat compares the 'Format' tag of songs with an audio format, or
+// with a mask in which any part may be '*'.
+func (p *filterParser) audioFormat(op, v string) (matcher, error) {
+	want := strings.Split(v, ":")
+
+	switch op {
+	case "==":
+		return func(s *Song) bool {
+			f, _ := s.tag("Format")
+			return f == v
+		}, nil
+
+	case "=~":
+		return func(s *Song) bool {
+			f, _ := s.tag("Format")
+			have := strings.Split(f, ":")
+			if len(have) != len(want) {
+				return false
+			}
+
+			for i := range want {
+				if want[i] != "*" && want[i] != have[i] {
+					return false
+				}
+			}
+			return true
+		}, nil
+	}
+
+	return nil, p.errorf("Unknown AudioFormat operator: %s", op)
+}
+
+// tagValues returns the values of a tag, including the special tags "file"
+// and "any".
+func tagValues(s *Song, tag string) []string {
+	switch strings.ToLower(tag) {
+	case "file":
+		return []string{s.File}
+	case "any":
+		values := []string{s.File}
+		for _, t := range s.Tags {
+			values = append(values, t.Value)
+		}
+		return values
+	}
+	return s.tagValues(tag)
+}
+
+// parseTime parses a time stamp given either as seconds since the Unix
+// epoch or in ISO 8601 format.
+func parseTime(v string) (time.Time, error) {
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
@@ -0,0 +1,109 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpdtest
+
+import "sort"
+
+// message is a message waiting to be read by a client.
+type message struct {
+	channel string
+	text    string
+}
+
+// validChannel reports whether name is a valid channel name: it consists of
+// letters, digits and the characters '_', '-', '.' and ':'.
+func validChannel(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '_' || r == '-' || r == '.' || r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) cmdSubscribe(c *conn, r *Response, args []string) error {
+	if !validChannel(args[0]) {
+		return Errorf(AckArg, "invalid channel name")
+	}
+
+	if c.channels[args[0]] {
+		return Errorf(AckExist, "already subscribed to this channel")
+	}
+
+	if c.channels == nil {
+		c.channels = make(map[string]bool)
+	}
+
+	c.channels[args[0]] = true
+	s.notify("subscription")
+	return nil
+}
+
+func (s *Server) cmdUnsubscribe(c *conn, r *Response, args []string) error {
+	if !c.channels[args[0]] {
+		return Errorf(AckNoExist, "not subscribed to this channel")
+	}
+
+	delete(c.channels, args[0])
+	s.notify("subscription")
+	return nil
+}
+
+func (s *Server) cmdChannels(c *conn, r *Response, args []string) error {
+	seen := make(map[string]bool)
+	for other := range s.conns {
+		for name := range other.channels {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		r.Add("channel", name)
+	}
+	return nil
+}
+
+func (s *Server) cmdReadMessages(c *conn, r *Response, args []string) error {
+	for _, m := range c.messages {
+		r.Add("channel", m.channel)
+		r.Add("message", m.text)
+	}
+
+	c.messages = nil
+	return nil
+}
+
+func (s *Server) cmdSendMessage(c *conn, r *Response, args []string) error {
+	if !validChannel(args[0]) {
+		return Errorf(AckArg, "invalid channel name")
+	}
+
+	sent := false
+	for other := range s.conns {
+		if other.channels[args[0]] {
+			other.messages = append(other.messages, message{args[0], args[1]})
+			other.notify("message")
+			sent = true
+		}
+	}
+
+	if !sent {
+		return Errorf(AckNoExist, "nobody is subscribed to this channel")
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpdtest
+
+import "sort"
+
+// partitionState is the queue and player state of a partition which is not
+// the one loaded into the server.
+type partitionState struct {
+	queue     []*queued
+	nextId    int
+	plVersion int
+	player    player
+}
+
+// partitionScoped lists the subsystems whose changes only concern the
+// clients in the partition they were made in.
+var partitionScoped = map[string]bool{
+	"playlist": true,
+	"player":   true,
+	"mixer":    true,
+	"options":  true,
+}
+
+// switchTo loads the state of the named partition into the server, after
+// putting away that of the current one. s.mu must be held.
+func (s *Server) switchTo(name string) {
+	if name == s.partition {
+		return
+	}
+
+	next, ok := s.partitions[name]
+	if !ok {
+		return
+	}
+
+	s.partitions[s.partition] = &partitionState{s.queue, s.nextId, s.plVersion, s.player}
+	s.partitions[name] = nil
+
+	s.queue = next.queue
+	s.nextId = next.nextId
+	s.plVersion = next.plVersion
+	s.player = next.player
+	s.partition = name
+}
+
+func (s *Server) cmdPartition(c *conn, r *Response, args []string) error {
+	if _, ok := s.partitions[args[0]]; !ok {
+		return Errorf(AckNoExist, "partition does not exist")
+	}
+
+	c.partition = args[0]
+	return nil
+}
+
+func (s *Server) cmdListPartitions(c *conn, r *Response, args []string) error {
+	names := make([]string, 0, len(s.partitions))
+	for name := range s.partitions {
+		if name != "default" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	r.Add("partition", "default")
+	for _, name := range names {
+		r.Add("partition", name)
+	}
+	return nil
+}
+
+func (s *Server) cmdNewPartition(c *conn, r *Response, args []string) error {
+	if _, ok := s.partitions[args[0]]; ok {
+		return Errorf(AckExist, "name already exists")
+	}
+
+	if !validChannel(args[0]) {
+		return Errorf(AckArg, "bad name")
+	}
+
+	s.partitions[args[0]] = &partitionState{
+		nextId:    1,
+		plVersion: 1,
+		player:    player{state: "stop", volume: 100, song: -1},
+	}
+
+	s.notify("partition")
+	return nil
+}
+
+func (s *Server) cmdDelPartition(c *conn, r *Response, args []string) error {
+	name := args[0]
+
+	if name == "default" {
+		return Errorf(AckArg, "cannot delete the default partition")
+	}
+
+	if _, ok := s.partitions[name]; !ok {
+		return Errorf(AckNoExist, "no such partition")
+	}
+
+	for other := range s.conns {
+		if other.partition == name {
+			return Errorf(AckArg, "partition still has clients")
+		}
+	}
+
+	for _, o := range s.outputs {
+		if o.partition == name {
+			o.partition = "default"
+		}
+	}
+
+	delete(s.partitions, name)
+	s.notify("partition", "output")
+	return nil
+}
+
+func (s *Server) cmdMoveOutput(c *conn, r *Response, args []string) error {
+	for _, o := range s.outputs {
+		if o.name == args[0] {
+			o.partition = s.partition
+			s.notify("output")
+			return nil
+		}
+	}
+
+	return Errorf(AckNoExist, "no such output")
+}
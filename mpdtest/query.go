@@ -0,0 +1,104 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpdtest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// query holds the options which may follow the filter of find, search and
+// similar commands: sort, window and position.
+type query struct {
+	sort     string // Tag to sort by, or empty.
+	desc     bool   // Sort in descending order.
+	window   bool   // Whether start and end are set.
+	start    int
+	end      int
+	position string // Insert position for findadd and searchadd, or empty.
+}
+
+// splitQuery removes the trailing options from args.
+func splitQuery(args []string) ([]string, query, error) {
+	var q query
+
+	for len(args) >= 3 {
+		name, v := args[len(args)-2], args[len(args)-1]
+
+		switch name {
+		case "sort":
+			q.sort = v
+			if q.desc = strings.HasPrefix(v, "-"); q.desc {
+				q.sort = v[1:]
+			}
+
+		case "window":
+			start, end, ok := strings.Cut(v, ":")
+			if !ok {
+				return nil, q, Errorf(AckArg, "Range expected: %s", v)
+			}
+
+			var err error
+			if q.start, err = strconv.Atoi(start); err != nil || q.start < 0 {
+				return nil, q, Errorf(AckArg, "Number expected: %s", start)
+			}
+
+			q.end = -1
+			if len(end) > 0 {
+				if q.end, err = strconv.Atoi(end); err != nil || q.end < q.start {
+					return nil, q, Errorf(AckArg, "Invalid range end: %s", end)
+				}
+			}
+			q.window = true
+
+		case "position":
+			q.position = v
+
+		default:
+			return args, q, nil
+		}
+
+		args = args[:len(args)-2]
+	}
+
+	return args, q, nil
+}
+
+// order sorts and windows n items, the songs of which are returned by song.
+// It returns the indices of the selected items in order.
+func (q *query) order(n int, song func(i int) *Song) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+
+	if len(q.sort) > 0 {
+		key := func(i int) string {
+			if strings.EqualFold(q.sort, "Last-Modified") {
+				return lastModified
+			}
+			v, _ := song(i).tag(q.sort)
+			return v
+		}
+
+		sort.SliceStable(idx, func(a, b int) bool {
+			if q.desc {
+				return key(idx[a]) > key(idx[b])
+			}
+			return key(idx[a]) < key(idx[b])
+		})
+	}
+
+	if q.window {
+		start, end := min(q.start, n), n
+		if q.end >= 0 {
+			end = min(q.end, n)
+		}
+		idx = idx[start:end]
+	}
+
+	return idx
+}
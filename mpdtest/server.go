@@ -0,0 +1,592 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+// Package mpdtest provides an in-memory MPD server for tests.
+//
+// The server speaks the MPD line protocol on a loopback listener, or on an
+// in-memory pipe, and keeps a fake database, queue, stored playlists,
+// outputs and player state. Changes to that state are reported to idling
+// clients like a real server would.
+//
+//     srv := mpdtest.NewServer()
+//     defer srv.Close()
+//
+//     srv.AddSong("Tool/Lateralus/01 The Grudge.flac",
+//         "Artist", "Tool", "Album", "Lateralus", "Title", "The Grudge")
+//
+//     c, err := mpd.Dial(srv.Addr(), "")
+//
+// Commands the server does not know, or whose behaviour a test needs to
+// control, can be scripted with Handle.
+package mpdtest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultVersion is the protocol version the server announces, unless
+// changed with SetVersion.
+const DefaultVersion = "0.23.5"
+
+// Error codes sent in ACK lines.
+const (
+	AckNotList       = 1
+	AckArg           = 2
+	AckPassword      = 3
+	AckPermission    = 4
+	AckUnknown       = 5
+	AckNoExist       = 50
+	AckPlaylistMax   = 51
+	AckSystem        = 52
+	AckPlaylistLoad  = 53
+	AckUpdateAlready = 54
+	AckPlayerSync    = 55
+	AckExist         = 56
+)
+
+// Ack is an error reported to the client in an ACK line. Handlers return it
+// to make a command fail with a specific code.
+type Ack struct {
+	Code    int
+	Message string
+}
+
+func (a *Ack) Error() string {
+	return a.Message
+}
+
+// Errorf returns an Ack with the given code and a formatted message.
+func Errorf(code int, format string, arg ...interface{}) *Ack {
+	return &Ack{code, fmt.Sprintf(format, arg...)}
+}
+
+// HandlerFunc implements a command. It appends the response to r and
+// returns nil, or returns an error to make the command fail. Errors other
+// than *Ack are reported with AckUnknown.
+type HandlerFunc func(r *Response, args []string) error
+
+// Response collects the lines of a response.
+type Response struct {
+	buf strings.Builder
+}
+
+// Add appends a 'key: value' line.
+func (r *Response) Add(key, value string) {
+	r.buf.WriteString(key)
+	r.buf.WriteString(": ")
+	r.buf.WriteString(value)
+	r.buf.WriteByte('\n')
+}
+
+// Addf appends a 'key: value' line with a formatted value.
+func (r *Response) Addf(key, format string, arg ...interface{}) {
+	r.Add(key, fmt.Sprintf(format, arg...))
+}
+
+// Binary appends a binary payload, announced by a 'binary' line.
+func (r *Response) Binary(data []byte) {
+	r.Addf("binary", "%d", len(data))
+	r.buf.Write(data)
+	r.buf.WriteByte('\n')
+}
+
+// Server is an in-memory MPD server. Its methods are safe for concurrent
+// use, also while clients are connected.
+type Server struct {
+	mu       sync.Mutex
+	ln       net.Listener
+	wg       sync.WaitGroup
+	version  string
+	password string
+	handlers map[string]HandlerFunc
+	conns    map[*conn]struct{}
+	closed   bool
+
+	db        map[string]*Song
+	queue     []*queued
+	nextId    int
+	plVersion int
+	playlists map[string][]string
+	outputs   []*output
+	player    player
+	updateId  int
+	stickers  map[stickerKey]map[string]string
+
+	// The queue, player and version above belong to the partition named
+	// partition. Those of the others are kept in partitions.
+	partition  string
+	partitions map[string]*partitionState
+}
+
+// Song is a song in the fake database.
+type Song struct {
+	File string
+	Tags []Tag
+}
+
+// Tag is a single tag of a song. Tags may repeat.
+type Tag struct {
+	Name  string
+	Value string
+}
+
+// NewServer starts a server listening on a loopback address. It must be
+// stopped with Close.
+func NewServer() *Server {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		if ln, err = net.Listen("tcp6", "[::1]:0"); err != nil {
+			panic(fmt.Sprintf("mpdtest: failed to listen on a port: %v", err))
+		}
+	}
+
+	s := NewUnstartedServer()
+	s.ln = ln
+
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// NewUnstartedServer returns a server which does not listen on any address.
+// Connections to it are made with Pipe.
+func NewUnstartedServer() *Server {
+	s := new(Server)
+	s.version = DefaultVersion
+	s.handlers = make(map[string]HandlerFunc)
+	s.conns = make(map[*conn]struct{})
+	s.db = make(map[string]*Song)
+	s.playlists = make(map[string][]string)
+	s.player = player{state: "stop", volume: 100, song: -1}
+	s.nextId = 1
+	s.plVersion = 1
+	s.partition = "default"
+	s.partitions = map[string]*partitionState{"default": nil}
+	return s
+}
+
+// Addr returns the host:port address the server listens on.
+func (s *Server) Addr() string {
+	if s.ln == nil {
+		return ""
+	}
+	return s.ln.Addr().String()
+}
+
+// Pipe returns the client end of a new in-memory connection to the server.
+func (s *Server) Pipe() net.Conn {
+	cc, sc := net.Pipe()
+
+	s.wg.Add(1)
+	go s.handle(sc)
+	return cc
+}
+
+// Close stops the listener, closes all connections and waits for their
+// goroutines to finish.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for c := range s.conns {
+		c.rwc.Close()
+	}
+	s.mu.Unlock()
+
+	var err error
+	if s.ln != nil {
+		err = s.ln.Close()
+	}
+
+	s.wg.Wait()
+	return err
+}
+
+// SetVersion sets the protocol version announced to new connections.
+func (s *Server) SetVersion(v string) {
+	s.mu.Lock()
+	s.version = v
+	s.mu.Unlock()
+}
+
+// SetPassword makes the server require the given password before accepting
+// any command other than 'password', 'ping' and 'close'. An empty password
+// disables the check.
+func (s *Server) SetPassword(password string) {
+	s.mu.Lock()
+	s.password = password
+	s.mu.Unlock()
+}
+
+// Handle registers a handler for the named command, replacing the built-in
+// implementation, if any. Handlers run without any lock held, so they may
+// call the methods of the server.
+func (s *Server) Handle(name string, h HandlerFunc) {
+	s.mu.Lock()
+	s.handlers[name] = h
+	s.mu.Unlock()
+}
+
+// Notify reports changes in the given subsystems to all clients, as if the
+// server state had changed.
+func (s *Server) Notify(subsystems ...string) {
+	s.mu.Lock()
+	s.notify(subsystems...)
+	s.mu.Unlock()
+}
+
+// notify reports changes to all clients. Changes to the queue, player,
+// mixer and options are only reported to the clients in the partition they
+// were made in. s.mu must be held.
+func (s *Server) notify(subsystems ...string) {
+	var global, local []string
+	for _, name := range subsystems {
+		if partitionScoped[name] {
+			local = append(local, name)
+		} else {
+			global = append(global, name)
+		}
+	}
+
+	for c := range s.conns {
+		if c.partition == s.partition {
+			c.notify(subsystems...)
+		} else if len(global) > 0 {
+			c.notify(global...)
+		}
+	}
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		rwc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		s.wg.Add(1)
+		go s.handle(rwc)
+	}
+}
+
+// conn is a single client connection.
+type conn struct {
+	rwc       net.Conn
+	authed    bool
+	partition string          // Partition the connection acts on.
+	pending   map[string]bool // Subsystems changed since the last idle.
+	wake      chan struct{}   // Signalled when pending changes.
+
+	channels map[string]bool // Subscribed channels.
+	messages []message       // Messages not read yet.
+}
+
+func (c *conn) notify(subsystems ...string) {
+	for _, name := range subsystems {
+		c.pending[name] = true
+	}
+
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Server) handle(rwc net.Conn) {
+	defer s.wg.Done()
+	defer rwc.Close()
+
+	c := &conn{
+		rwc:       rwc,
+		partition: "default",
+		pending:   make(map[string]bool),
+		wake:      make(chan struct{}, 1),
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.conns[c] = struct{}{}
+	c.authed = len(s.password) == 0
+	version := s.version
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, c)
+		s.mu.Unlock()
+	}()
+
+	// The reader ends when the connection is closed on return, or when
+	// done is closed while it waits to hand over a line.
+	lines := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(lines)
+
+		r := bufio.NewReader(rwc)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			select {
+			case lines <- strings.TrimRight(line, "\r\n"):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	w := bufio.NewWriter(rwc)
+	fmt.Fprintf(w, "OK MPD %s\n", version)
+	if w.Flush() != nil {
+		return
+	}
+
+	var list []string
+	var inList, listOK bool
+
+	for line := range lines {
+		name, args, err := tokenize(line)
+
+		switch {
+		case inList && name == "command_list_end":
+			s.runList(w, c, list, listOK)
+			list, inList = nil, false
+		case inList:
+			list = append(list, line)
+			continue
+		case err != nil:
+			writeAck(w, &Ack{AckArg, err.Error()}, 0, "")
+		case name == "command_list_begin" || name == "command_list_ok_begin":
+			inList, listOK = true, name == "command_list_ok_begin"
+			continue
+		case name == "close":
+			return
+		case name == "noidle":
+			// Not idling, so there is nothing to cancel.
+			continue
+		case name == "idle":
+			if !s.idle(w, c, args, lines) {
+				return
+			}
+		default:
+			var r Response
+			if err := s.exec(c, &r, name, args); err != nil {
+				writeAck(w, err, 0, name)
+			} else {
+				w.WriteString(r.buf.String())
+				w.WriteString("OK\n")
+			}
+		}
+
+		if w.Flush() != nil {
+			return
+		}
+	}
+}
+
+// runList executes the commands of a command list.
+func (s *Server) runList(w *bufio.Writer, c *conn, list []string, listOK bool) {
+	for i, line := range list {
+		var r Response
+
+		name, args, err := tokenize(line)
+		if err == nil {
+			err = s.exec(c, &r, name, args)
+		}
+
+		if err != nil {
+			writeAck(w, err, i, name)
+			return
+		}
+
+		w.WriteString(r.buf.String())
+		if listOK {
+			w.WriteString("list_OK\n")
+		}
+	}
+
+	w.WriteString("OK\n")
+}
+
+// idle waits until one of the requested subsystems changes or the client
+// sends noidle. It reports false if the connection was closed.
+func (s *Server) idle(w *bufio.Writer, c *conn, args []string, lines <-chan string) bool {
+	changed := func() (list []string) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		for name := range c.pending {
+			if len(args) == 0 || contains(args, name) {
+				list = append(list, name)
+				delete(c.pending, name)
+			}
+		}
+
+		sort.Strings(list)
+		return
+	}
+
+	for {
+		if list := changed(); len(list) > 0 {
+			for _, name := range list {
+				fmt.Fprintf(w, "changed: %s\n", name)
+			}
+			w.WriteString("OK\n")
+			return true
+		}
+
+		select {
+		case <-c.wake:
+		case line, ok := <-lines:
+			if !ok {
+				return false
+			}
+
+			if strings.TrimSpace(line) != "noidle" {
+				// A real server drops clients which do this.
+				return false
+			}
+
+			for _, name := range changed() {
+				fmt.Fprintf(w, "changed: %s\n", name)
+			}
+			w.WriteString("OK\n")
+			return true
+		}
+	}
+}
+
+// exec runs a single command.
+func (s *Server) exec(c *conn, r *Response, name string, args []string) error {
+	s.mu.Lock()
+
+	if !c.authed && name != "password" && name != "ping" {
+		s.mu.Unlock()
+		return Errorf(AckPermission, "you don't have permission for %q", name)
+	}
+
+	if h, ok := s.handlers[name]; ok {
+		s.mu.Unlock()
+		return h(r, args)
+	}
+
+	defer s.mu.Unlock()
+	s.switchTo(c.partition)
+
+	if name == "password" {
+		if len(args) != 1 {
+			return errArgCount(name)
+		}
+
+		if args[0] != s.password {
+			return Errorf(AckPassword, "incorrect password")
+		}
+
+		c.authed = true
+		return nil
+	}
+
+	if cmd, ok := connCommands[name]; ok {
+		if len(args) < cmd.min || (cmd.max >= 0 && len(args) > cmd.max) {
+			return errArgCount(name)
+		}
+		return cmd.fn(s, c, r, args)
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		return Errorf(AckUnknown, "unknown command %q", name)
+	}
+
+	if len(args) < cmd.min || (cmd.max >= 0 && len(args) > cmd.max) {
+		return errArgCount(name)
+	}
+
+	return cmd.fn(s, r, args)
+}
+
+func writeAck(w *bufio.Writer, err error, index int, name string) {
+	ack, ok := err.(*Ack)
+	if !ok {
+		ack = &Ack{AckUnknown, err.Error()}
+	}
+	fmt.Fprintf(w, "ACK [%d@%d] {%s} %s\n", ack.Code, index, name, ack.Message)
+}
+
+func errArgCount(name string) *Ack {
+	return Errorf(AckArg, "wrong number of arguments for %q", name)
+}
+
+// tokenize splits a command line into the command name and its arguments.
+// Arguments are separated by whitespace, or enclosed in double quotes, in
+// which a backslash escapes the next character.
+func tokenize(line string) (name string, args []string, err error) {
+	line = strings.TrimSpace(line)
+
+	for len(line) > 0 {
+		var arg string
+
+		if line[0] == '"' {
+			var b strings.Builder
+			i := 1
+
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' {
+					if i++; i == len(line) {
+						break
+					}
+				}
+				b.WriteByte(line[i])
+			}
+
+			if i >= len(line) {
+				return "", nil, io.ErrUnexpectedEOF
+			}
+
+			arg, line = b.String(), line[i+1:]
+		} else {
+			i := strings.IndexAny(line, " \t")
+			if i == -1 {
+				i = len(line)
+			}
+			arg, line = line[:i], line[i:]
+		}
+
+		if len(name) == 0 {
+			name = arg
+		} else {
+			args = append(args, arg)
+		}
+
+		line = strings.TrimLeft(line, " \t")
+	}
+
+	return
+}
+
+func contains(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
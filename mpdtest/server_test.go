@@ -0,0 +1,74 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpdtest
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		line string
+		name string
+		args []string
+	}{
+		{"status", "status", nil},
+		{`add "Björk/Homogenic/01 Hunter.flac"`, "add", []string{"Björk/Homogenic/01 Hunter.flac"}},
+		{`find "artist" "say \"hi\"\\"`, "find", []string{"artist", `say "hi"\`}},
+		{"seek  3\t120", "seek", []string{"3", "120"}},
+		{`find "" x`, "find", []string{"", "x"}},
+	}
+
+	for _, tt := range tests {
+		name, args, err := tokenize(tt.line)
+		if err != nil || name != tt.name || !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("tokenize(%q) = %q, %q, %v; want %q, %q",
+				tt.line, name, args, err, tt.name, tt.args)
+		}
+	}
+
+	if _, _, err := tokenize(`add "unterminated`); err == nil {
+		t.Error("tokenize accepted an unterminated quote")
+	}
+}
+
+func TestHandle(t *testing.T) {
+	srv := NewUnstartedServer()
+	defer srv.Close()
+
+	srv.Handle("greet", func(r *Response, args []string) error {
+		if len(args) != 1 {
+			return Errorf(AckArg, "wrong number of arguments")
+		}
+		r.Add("greeting", "hello "+args[0])
+		return nil
+	})
+
+	conn := srv.Pipe()
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	r.ReadString('\n')
+
+	io.WriteString(conn, "command_list_ok_begin\ngreet \"you\"\ngreet\ncommand_list_end\n")
+
+	var got []string
+	for len(got) < 3 {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, strings.TrimSpace(line))
+	}
+
+	want := []string{"greeting: hello you", "list_OK", "ACK [2@1] {greet} wrong number of arguments"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,291 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpdtest
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// lastModified is reported for every song and directory in the database.
+const lastModified = "2012-01-01T00:00:00Z"
+
+// queued is a song in the queue.
+type queued struct {
+	song *Song
+	id   int
+}
+
+// output is an audio output.
+type output struct {
+	id        int
+	name      string
+	plugin    string
+	enabled   bool
+	partition string
+
+	attributes map[string]string // Runtime attributes, set with outputset.
+}
+
+// player holds the playback state and options.
+type player struct {
+	state   string // One of play, pause or stop.
+	song    int    // Position of the current song in the queue, or -1.
+	elapsed float64
+	volume  int
+	repeat  bool
+	random  bool
+	single  string
+	consume bool
+	xfade   int
+}
+
+// AddSong adds a song to the database. Tags are given as name/value pairs
+// and are reported in the given order:
+//
+//     srv.AddSong("a.flac", "Artist", "Alice", "Artist", "Bob", "Title", "Duet")
+func (s *Server) AddSong(file string, tags ...string) {
+	song := &Song{File: file}
+	for i := 0; i+1 < len(tags); i += 2 {
+		song.Tags = append(song.Tags, Tag{tags[i], tags[i+1]})
+	}
+
+	s.mu.Lock()
+	s.db[file] = song
+	s.notify("database")
+	s.mu.Unlock()
+}
+
+// Queue returns the files in the queue of the default partition, in order.
+func (s *Server) Queue() (files []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.switchTo("default")
+
+	for _, q := range s.queue {
+		files = append(files, q.song.File)
+	}
+	return
+}
+
+// SetPlaylist creates or replaces a stored playlist.
+func (s *Server) SetPlaylist(name string, files ...string) {
+	s.mu.Lock()
+	s.playlists[name] = append([]string(nil), files...)
+	s.notify("stored_playlist")
+	s.mu.Unlock()
+}
+
+// Playlist returns the files in a stored playlist, and whether it exists.
+func (s *Server) Playlist(name string) ([]string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	files, ok := s.playlists[name]
+	return append([]string(nil), files...), ok
+}
+
+// AddOutput adds an audio output and returns its id.
+func (s *Server) AddOutput(name, plugin string, enabled bool) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	o := &output{id: len(s.outputs), name: name, plugin: plugin, enabled: enabled, partition: "default"}
+	s.outputs = append(s.outputs, o)
+	s.notify("output")
+	return o.id
+}
+
+// OutputEnabled reports whether the output with the given id is enabled.
+func (s *Server) OutputEnabled(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return id >= 0 && id < len(s.outputs) && s.outputs[id].enabled
+}
+
+// SetOutputAttribute sets a runtime attribute of the output with the given
+// id. Only attributes set this way can be changed with 'outputset'.
+func (s *Server) SetOutputAttribute(id int, name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 0 || id >= len(s.outputs) {
+		return
+	}
+
+	o := s.outputs[id]
+	if o.attributes == nil {
+		o.attributes = make(map[string]string)
+	}
+
+	o.attributes[name] = value
+	s.notify("output")
+}
+
+// OutputAttribute returns the value of a runtime attribute of the output
+// with the given id, and whether it is set.
+func (s *Server) OutputAttribute(id int, name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 0 || id >= len(s.outputs) {
+		return "", false
+	}
+
+	v, ok := s.outputs[id].attributes[name]
+	return v, ok
+}
+
+// OutputPartition returns the name of the partition the output with the given id
+// is in.
+func (s *Server) OutputPartition(id int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 0 || id >= len(s.outputs) {
+		return ""
+	}
+	return s.outputs[id].partition
+}
+
+// PlayerState returns the playback state of the default partition: play,
+// pause or stop.
+func (s *Server) PlayerState() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.switchTo("default")
+	return s.player.state
+}
+
+// Volume returns the volume of the default partition.
+func (s *Server) Volume() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.switchTo("default")
+	return s.player.volume
+}
+
+// tag returns the first value of the named tag, matched case-insensitively.
+func (song *Song) tag(name string) (string, bool) {
+	for _, t := range song.Tags {
+		if strings.EqualFold(t.Name, name) {
+			return t.Value, true
+		}
+	}
+	return "", false
+}
+
+// tagValues returns all values of the named tag.
+func (song *Song) tagValues(name string) (v []string) {
+	for _, t := range song.Tags {
+		if strings.EqualFold(t.Name, name) {
+			v = append(v, t.Value)
+		}
+	}
+	return
+}
+
+// songs returns the songs in the database at or below uri, sorted by file.
+func (s *Server) songs(uri string) (list []*Song) {
+	uri = strings.Trim(uri, "/")
+
+	for file, song := range s.db {
+		if len(uri) == 0 || file == uri || strings.HasPrefix(file, uri+"/") {
+			list = append(list, song)
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].File < list[j].File
+	})
+	return
+}
+
+// allSongs returns all songs in the database, sorted by file.
+func (s *Server) allSongs() []*Song {
+	return s.songs("")
+}
+
+// directories returns the directories in the database directly below uri,
+// sorted by name.
+func (s *Server) directories(uri string) (list []string) {
+	uri = strings.Trim(uri, "/")
+	seen := make(map[string]bool)
+
+	for file := range s.db {
+		dir := path.Dir(file)
+		for dir != "." && dir != "/" {
+			if path.Dir(dir) == uri || (len(uri) == 0 && path.Dir(dir) == ".") {
+				seen[dir] = true
+			}
+			dir = path.Dir(dir)
+		}
+	}
+
+	for dir := range seen {
+		list = append(list, dir)
+	}
+
+	sort.Strings(list)
+	return
+}
+
+// isDirectory reports whether uri is a directory in the database.
+func (s *Server) isDirectory(uri string) bool {
+	uri = strings.Trim(uri, "/")
+	if len(uri) == 0 {
+		return true
+	}
+
+	for file := range s.db {
+		if strings.HasPrefix(file, uri+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// queueChanged bumps the queue version and notifies clients.
+func (s *Server) queueChanged() {
+	s.plVersion++
+	s.notify("playlist")
+}
+
+// current returns the current song, if any.
+func (s *Server) current() *queued {
+	if s.player.song >= 0 && s.player.song < len(s.queue) {
+		return s.queue[s.player.song]
+	}
+	return nil
+}
+
+// position returns the queue position of the song with the given id.
+func (s *Server) position(id int) int {
+	for i, q := range s.queue {
+		if q.id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func writeSong(r *Response, song *Song) {
+	r.Add("file", song.File)
+	r.Add("Last-Modified", lastModified)
+	for _, t := range song.Tags {
+		r.Add(t.Name, t.Value)
+	}
+}
+
+func writeQueued(r *Response, q *queued, pos int) {
+	writeSong(r, q.song)
+	r.Addf("Pos", "%d", pos)
+	r.Addf("Id", "%d", q.id)
+}
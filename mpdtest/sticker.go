@@ -0,0 +1,235 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpdtest
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// stickerKey identifies the object a sticker is attached to.
+type stickerKey struct {
+	typ string
+	uri string
+}
+
+// Sticker returns the value of a sticker, and whether it exists.
+func (s *Server) Sticker(typ, uri, name string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.stickers[stickerKey{typ, uri}][name]
+	return v, ok
+}
+
+// SetSticker sets the value of a sticker.
+func (s *Server) SetSticker(typ, uri, name, value string) {
+	s.mu.Lock()
+	s.setSticker(stickerKey{typ, uri}, name, value)
+	s.mu.Unlock()
+}
+
+func (s *Server) setSticker(k stickerKey, name, value string) {
+	if s.stickers == nil {
+		s.stickers = make(map[stickerKey]map[string]string)
+	}
+
+	if s.stickers[k] == nil {
+		s.stickers[k] = make(map[string]string)
+	}
+
+	s.stickers[k][name] = value
+	s.notify("sticker")
+}
+
+// cmdSticker implements 'sticker get|set|inc|dec|delete|list|find TYPE URI
+// [...]'.
+func (s *Server) cmdSticker(r *Response, args []string) error {
+	op, k, args := args[0], stickerKey{args[1], args[2]}, args[3:]
+
+	var exists bool
+	switch k.typ {
+	case "song":
+		_, exists = s.db[k.uri]
+	case "playlist":
+		_, exists = s.playlists[k.uri]
+	default:
+		return Errorf(AckArg, "unknown sticker domain")
+	}
+
+	if op != "find" && !exists {
+		return Errorf(AckNoExist, "No such %s", k.typ)
+	}
+
+	switch op {
+	case "get":
+		if len(args) != 1 {
+			break
+		}
+
+		v, ok := s.stickers[k][args[0]]
+		if !ok {
+			return Errorf(AckNoExist, "no such sticker")
+		}
+		r.Add("sticker", args[0]+"="+v)
+		return nil
+
+	case "set":
+		if len(args) != 2 {
+			break
+		}
+
+		s.setSticker(k, args[0], args[1])
+		return nil
+
+	case "inc", "dec":
+		if len(args) != 2 {
+			break
+		}
+
+		n, err := parseInt(args[1])
+		if err != nil {
+			return err
+		}
+
+		if op == "dec" {
+			n = -n
+		}
+
+		old, _ := strconv.Atoi(s.stickers[k][args[0]])
+		s.setSticker(k, args[0], strconv.Itoa(old+n))
+		return nil
+
+	case "delete":
+		if len(args) > 1 {
+			break
+		}
+
+		if len(args) == 0 {
+			delete(s.stickers, k)
+		} else if _, ok := s.stickers[k][args[0]]; ok {
+			delete(s.stickers[k], args[0])
+		} else {
+			return Errorf(AckNoExist, "no such sticker")
+		}
+
+		s.notify("sticker")
+		return nil
+
+	case "list":
+		if len(args) != 0 {
+			break
+		}
+
+		names := make([]string, 0, len(s.stickers[k]))
+		for name := range s.stickers[k] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			r.Add("sticker", name+"="+s.stickers[k][name])
+		}
+		return nil
+
+	case "find":
+		return s.stickerFind(r, k, args)
+	}
+
+	return Errorf(AckArg, "bad request")
+}
+
+// stickerFind implements 'sticker find TYPE URI NAME [OP VALUE] [sort
+// TYPE] [window START:END]'.
+func (s *Server) stickerFind(r *Response, k stickerKey, args []string) error {
+	if len(args) == 0 {
+		return Errorf(AckArg, "bad request")
+	}
+
+	args, opts, err := splitQuery(args)
+	if err != nil {
+		return err
+	}
+
+	name := args[0]
+	match := func(string) bool { return true }
+
+	if len(args) == 3 {
+		op, want := args[1], args[2]
+		wantN, _ := strconv.Atoi(want)
+
+		switch op {
+		case "=", "eq":
+			match = func(v string) bool { return v == want }
+		case "<":
+			match = func(v string) bool { return v < want }
+		case ">":
+			match = func(v string) bool { return v > want }
+		case "lt":
+			match = func(v string) bool { n, _ := strconv.Atoi(v); return n < wantN }
+		case "gt":
+			match = func(v string) bool { n, _ := strconv.Atoi(v); return n > wantN }
+		case "contains":
+			match = func(v string) bool { return strings.Contains(v, want) }
+		case "starts_with":
+			match = func(v string) bool { return strings.HasPrefix(v, want) }
+		default:
+			return Errorf(AckArg, "bad operator")
+		}
+	} else if len(args) != 1 {
+		return Errorf(AckArg, "bad request")
+	}
+
+	type found struct{ uri, value string }
+	var list []found
+
+	prefix := strings.Trim(k.uri, "/")
+	for key, stickers := range s.stickers {
+		if key.typ != k.typ || (len(prefix) > 0 && key.uri != prefix && !strings.HasPrefix(key.uri, prefix+"/")) {
+			continue
+		}
+
+		if v, ok := stickers[name]; ok && match(v) {
+			list = append(list, found{key.uri, v})
+		}
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].uri < list[j].uri })
+
+	desc := opts.desc
+	switch opts.sort {
+	case "", "uri":
+	case "value":
+		sort.SliceStable(list, func(i, j int) bool { return list[i].value < list[j].value })
+	case "value_int":
+		sort.SliceStable(list, func(i, j int) bool {
+			a, _ := strconv.Atoi(list[i].value)
+			b, _ := strconv.Atoi(list[j].value)
+			return a < b
+		})
+	default:
+		return Errorf(AckArg, "Unknown sort tag")
+	}
+
+	if desc {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+
+	// Sorting has been done above.
+	opts.sort = ""
+	key := "file"
+	if k.typ == "playlist" {
+		key = "playlist"
+	}
+
+	for _, i := range opts.order(len(list), nil) {
+		r.Add(key, list[i].uri)
+		r.Add("sticker", name+"="+list[i].value)
+	}
+	return nil
+}
@@ -4,16 +4,37 @@
 
 package mpd
 
+import "strings"
+
+// Output is an audio output, as reported by Outputs.
 type Output struct {
-	Name    string
-	Id      int
-	Enabled bool
+	Name    string `mpd:"outputname"`
+	Id      int    `mpd:"outputid"`
+	Enabled bool   `mpd:"outputenabled"`
+	Plugin  string `mpd:"plugin"` // Eg: "alsa", "pulse" or "httpd".
+
+	// Runtime attributes of the output, such as "dop" or "allowed_formats".
+	// They depend on the plugin, and can be changed with OutputSet.
+	Attributes map[string]string `mpd:"-"`
+
+	Extra map[string][]string `mpd:"*"` // Fields not listed above.
 }
 
+// readOutput decodes an entry of the outputs response.
 func readOutput(a Args) *Output {
 	s := new(Output)
-	s.Id = a.I("outputid")
-	s.Name = a.S("outputname")
-	s.Enabled = a.I("outputenabled") == 1
+	Unmarshal(a, s)
+
+	// Attributes are sent as 'attribute: name=value' lines.
+	if values, ok := s.Extra["attribute"]; ok {
+		s.Attributes = make(map[string]string, len(values))
+		for _, v := range values {
+			name, value, _ := strings.Cut(v, "=")
+			s.Attributes[name] = value
+		}
+
+		delete(s.Extra, "attribute")
+	}
+
 	return s
 }
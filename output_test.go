@@ -0,0 +1,82 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestOutputs(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddOutput("Speakers", "alsa", true)
+	dac := srv.AddOutput("DAC", "alsa", false)
+	srv.SetOutputAttribute(dac, "dop", "0")
+	srv.SetOutputAttribute(dac, "allowed_formats", "")
+
+	c := dialTestServer(t, srv)
+
+	o, err := c.FindOutput("DAC")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Output{
+		Name:       "DAC",
+		Id:         dac,
+		Plugin:     "alsa",
+		Attributes: map[string]string{"dop": "0", "allowed_formats": ""},
+		Extra:      map[string][]string{},
+	}
+
+	if !reflect.DeepEqual(o, want) {
+		t.Fatalf("got %+v, want %+v", o, want)
+	}
+
+	if _, err = c.FindOutput("Headphones"); !errors.Is(err, ErrNoExist) {
+		t.Fatalf("got %v, want ErrNoExist", err)
+	}
+
+	if err = c.ToggleOutput(dac); err != nil || !srv.OutputEnabled(dac) {
+		t.Fatalf("toggle: %v, enabled %v", err, srv.OutputEnabled(dac))
+	}
+
+	if err = c.ToggleOutputName("DAC"); err != nil || srv.OutputEnabled(dac) {
+		t.Fatalf("toggle by name: %v, enabled %v", err, srv.OutputEnabled(dac))
+	}
+
+	if err = c.EnableOutputName("DAC"); err != nil || !srv.OutputEnabled(dac) {
+		t.Fatalf("enable by name: %v, enabled %v", err, srv.OutputEnabled(dac))
+	}
+
+	if err = c.DisableOutputName("Speakers"); err != nil || srv.OutputEnabled(0) {
+		t.Fatalf("disable by name: %v, enabled %v", err, srv.OutputEnabled(0))
+	}
+
+	if err = c.OutputSet(dac, "dop", "1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.OutputSetName("DAC", "allowed_formats", "dsd64:2 *:*:*"); err != nil {
+		t.Fatal(err)
+	}
+
+	if o, err = c.FindOutput("DAC"); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Attributes["dop"] != "1" || o.Attributes["allowed_formats"] != "dsd64:2 *:*:*" {
+		t.Fatalf("got attributes %q", o.Attributes)
+	}
+
+	if err = c.OutputSet(dac, "volume", "1"); !errors.Is(err, ErrArg) {
+		t.Fatalf("got %v, want ErrArg", err)
+	}
+
+	if err = c.EnableOutputName("Headphones"); !errors.Is(err, ErrNoExist) {
+		t.Fatalf("got %v, want ErrNoExist", err)
+	}
+}
@@ -0,0 +1,155 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPartitions(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddOutput("Living room", "alsa", true)
+	srv.AddOutput("Kitchen", "pulse", true)
+
+	c := dialTestServer(t, srv)
+
+	if err := c.NewPartition("kitchen"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.NewPartition("kitchen"); !errors.Is(err, ErrExist) {
+		t.Fatalf("got %v, want ErrExist", err)
+	}
+
+	if list, err := c.ListPartitions(); err != nil || !reflect.DeepEqual(list, []string{"default", "kitchen"}) {
+		t.Fatalf("got partitions %q, %v", list, err)
+	}
+
+	kitchen := c.Partition("kitchen")
+
+	if err := kitchen.MoveOutput("Kitchen"); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := srv.OutputPartition(1); p != "kitchen" {
+		t.Fatalf("output is in partition %q, want kitchen", p)
+	}
+
+	if err := kitchen.Add("Björk/Homogenic/01 Hunter.flac"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := kitchen.Play(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The unbound client still acts on the default partition.
+	if q := srv.Queue(); len(q) != 0 {
+		t.Fatalf("default queue holds %q", q)
+	}
+
+	if st, err := c.Status(); err != nil || st.Partition != "default" || st.State == Playing {
+		t.Fatalf("got status %+v, %v", st, err)
+	}
+
+	outputs, err := kitchen.Outputs()
+	if err != nil || len(outputs) != 1 || outputs[0].Name != "Kitchen" {
+		t.Fatalf("got outputs %+v, %v", outputs, err)
+	}
+
+	// The binding survives a new connection.
+	if err = c.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := kitchen.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if st.Partition != "kitchen" || st.State != Playing || st.PlaylistLength != 1 {
+		t.Fatalf("got status %+v", st)
+	}
+
+	if err = c.DelPartition("default"); !errors.Is(err, ErrArg) {
+		t.Fatalf("got %v, want ErrArg", err)
+	}
+
+	other := dialTestServer(t, srv)
+	if err = other.SwitchPartition("kitchen"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.DelPartition("kitchen"); !errors.Is(err, ErrArg) {
+		t.Fatalf("deleting a partition in use: got %v, want ErrArg", err)
+	}
+
+	// Once no connection is in it, the partition can be deleted. Its
+	// outputs return to the default partition.
+	if err = other.SwitchPartition("default"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = c.DelPartition("kitchen"); err != nil {
+		t.Fatal(err)
+	}
+
+	if p := srv.OutputPartition(1); p != "default" {
+		t.Fatalf("output is in partition %q, want default", p)
+	}
+
+	if err = c.SwitchPartition("kitchen"); !errors.Is(err, ErrNoExist) {
+		t.Fatalf("got %v, want ErrNoExist", err)
+	}
+}
+
+func TestReconnectVersion(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetVersion("0.21.0")
+
+	c := dialTestServer(t, srv)
+	if err := c.NewPartition("kitchen"); err != nil {
+		t.Fatal(err)
+	}
+
+	kitchen := c.Partition("kitchen")
+
+	var ue *UnsupportedError
+	if err := kitchen.DelPartition("other"); !errors.As(err, &ue) || ue.Have != (Version{0, 21, 0}) {
+		t.Fatalf("got %v, want unsupported error", err)
+	}
+
+	// The bound client sees the version of the server it is connected to
+	// after the upgrade.
+	srv.SetVersion("0.23.5")
+	if err := c.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if kitchen.ServerVersion() != (Version{0, 23, 5}) || kitchen.ProtocolVersion() != "MPD 0.23.5" {
+		t.Fatalf("bound client has version %v (%q)", kitchen.ServerVersion(), kitchen.ProtocolVersion())
+	}
+
+	if err := kitchen.DelPartition("other"); !errors.Is(err, ErrNoExist) {
+		t.Fatalf("got %v, want ErrNoExist", err)
+	}
+
+	// And after a downgrade.
+	srv.SetVersion("0.20.0")
+	if err := c.Reconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := kitchen.Status(); !errors.As(err, &ue) || ue.Command != "partition" || ue.Have != (Version{0, 20, 0}) {
+		t.Fatalf("got %v, want unsupported error", err)
+	}
+
+	if err := c.WithContext(context.Background()).NewPartition("hall"); !errors.As(err, &ue) || ue.Have != (Version{0, 20, 0}) {
+		t.Fatalf("got %v, want unsupported error", err)
+	}
+}
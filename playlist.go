@@ -1,13 +1,13 @@
 package mpd
 
 type Playlist struct {
-	Name string
-	LastModified string
+	Name         string `mpd:"playlist"`
+	LastModified string `mpd:"Last-Modified"`
 }
 
+// readPlaylist decodes an entry of the listplaylists response.
 func readPlaylist(a Args) *Playlist {
 	p := new(Playlist)
-	p.Name = a.S("playlist")
-	p.LastModified = a.S("Last-Modified")
+	Unmarshal(a, p)
 	return p
-}
\ No newline at end of file
+}
@@ -0,0 +1,126 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"iter"
+	"strconv"
+)
+
+// QueryOption modifies the result of find, search and similar commands.
+// Options are created with Sort, SortDesc, Window and Position, and may be
+// given in any order.
+type QueryOption struct {
+	name  string
+	value string
+	need  Version
+}
+
+// Positions of the options in a command. The server expects them in this
+// order.
+var queryOptionOrder = []string{"sort", "window", "position"}
+
+// Sort orders the result by the given tag, in ascending order. Besides tags,
+// "Last-Modified" is accepted, and "Added" since protocol version 0.24.
+func Sort(tag string) QueryOption {
+	return QueryOption{name: "sort", value: tag, need: versionQueryOptions}
+}
+
+// SortDesc is like Sort, but orders the result in descending order.
+func SortDesc(tag string) QueryOption {
+	return QueryOption{name: "sort", value: "-" + tag, need: versionQueryOptions}
+}
+
+// Window limits the result to the songs at positions start up to, but not
+// including, end. An end of -1 includes all songs after start.
+func Window(start, end int) QueryOption {
+	v := strconv.Itoa(start) + ":"
+	if end >= 0 {
+		v += strconv.Itoa(end)
+	}
+	return QueryOption{name: "window", value: v, need: versionQueryOptions}
+}
+
+// Position makes findadd and searchadd insert the songs into the queue at
+// pos, instead of appending them. It requires protocol version 0.23 and is
+// not valid for other commands.
+func Position(pos int) QueryOption {
+	return QueryOption{name: "position", value: strconv.Itoa(pos), need: versionAddPosition}
+}
+
+// queryArgs returns the arguments of a command taking a filter and options,
+// and the oldest protocol version supporting them.
+func queryArgs(f Filter, opts []QueryOption) (args []interface{}, need Version, err error) {
+	if err = f.check(false); err != nil {
+		return
+	}
+
+	args = append(args, f)
+	need = f.need
+
+	for _, name := range queryOptionOrder {
+		for _, o := range opts {
+			if o.name != name {
+				continue
+			}
+
+			args = append(args, o.name, o.value)
+			if !need.AtLeast(o.need) {
+				need = o.need
+			}
+			break
+		}
+	}
+
+	return
+}
+
+// FindPages is like FindFilter, but fetches the result size songs at a time
+// and yields each page as it arrives. Unlike the other sequences, the
+// client may be used inside the loop. Give a Sort option to keep the order
+// stable while the database changes. A Window option is ignored.
+func (c *Client) FindPages(f Filter, size int, opts ...QueryOption) iter.Seq2[[]*Song, error] {
+	return c.pages("find", f, size, opts)
+}
+
+// SearchPages is like SearchFilter, but fetches the result page by page, as
+// described for FindPages.
+func (c *Client) SearchPages(f Filter, size int, opts ...QueryOption) iter.Seq2[[]*Song, error] {
+	return c.pages("search", f, size, opts)
+}
+
+// pages yields the result of a query size songs at a time.
+func (c *Client) pages(cmd string, f Filter, size int, opts []QueryOption) iter.Seq2[[]*Song, error] {
+	var rest []QueryOption
+	for _, o := range opts {
+		if o.name != "window" {
+			rest = append(rest, o)
+		}
+	}
+
+	return func(yield func([]*Song, error) bool) {
+		if size <= 0 {
+			yield(nil, errors.New("Page size must be positive."))
+			return
+		}
+
+		for start := 0; ; start += size {
+			page, err := c.filterSongs(cmd, f, append(rest, Window(start, start+size))...)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if len(page) > 0 && !yield(page, nil) {
+				return
+			}
+
+			if len(page) < size {
+				return
+			}
+		}
+	}
+}
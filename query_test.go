@@ -0,0 +1,134 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestQueryArgs(t *testing.T) {
+	args, need, _ := queryArgs(Eq("artist", "Tool"), []QueryOption{Position(3), Window(0, 50), SortDesc("Date")})
+
+	cmd := NewCommand("findadd", args...).String()
+	if want := `findadd "(artist == 'Tool')" "sort" "-Date" "window" "0:50" "position" "3"`; cmd != want {
+		t.Errorf("got %s, want %s", cmd, want)
+	}
+
+	if need != versionAddPosition {
+		t.Errorf("got required version %v", need)
+	}
+
+	if args, _, _ = queryArgs(Base("Tool"), []QueryOption{Window(10, -1)}); args[2] != "10:" {
+		t.Errorf("got window %v", args[2])
+	}
+}
+
+func TestQueryOptions(t *testing.T) {
+	srv := newTestServer(t)
+	c := dialTestServer(t, srv)
+
+	songs, err := c.FindFilter(Base("Tool"), SortDesc("Title"))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(songs) != 2 || songs[0].Title != "The Grudge" {
+		t.Fatalf("unexpected sorted result: %+v", songs)
+	}
+
+	songs, err = c.SearchFilter(Contains("any", ""), Sort("Title"), Window(1, 2))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(songs) != 1 || songs[0].Title != "Hunter" {
+		t.Fatalf("unexpected windowed result: %+v", songs)
+	}
+
+	// Sorting playlistfind requires 0.24.
+	if _, err = c.PlaylistFindFilter(Base("Tool"), Sort("Title")); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("got %v, want unsupported error", err)
+	}
+}
+
+func TestPages(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddSong("Tool/Lateralus/03 Schism.flac", "Artist", "Tool", "Album", "Lateralus", "Title", "Schism")
+
+	c := dialTestServer(t, srv)
+
+	var titles []string
+	var sizes []int
+
+	for page, err := range c.FindPages(Contains("file", ""), 2, Sort("Title"), Window(0, 1)) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sizes = append(sizes, len(page))
+		for _, s := range page {
+			titles = append(titles, s.Title)
+		}
+
+		// The client may be used between pages.
+		if _, err = c.Status(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(sizes) != 2 || sizes[0] != 2 || sizes[1] != 2 {
+		t.Fatalf("got pages of %v songs, want 2 and 2", sizes)
+	}
+
+	if titles[0] != "Eon Blue Apocalypse" || titles[3] != "The Grudge" {
+		t.Fatalf("unexpected order: %q", titles)
+	}
+
+	for page, err := range c.SearchPages(Eq("artist", "tool"), 2) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(page) != 2 {
+			t.Fatalf("got page of %d songs, want 2", len(page))
+		}
+		break
+	}
+}
+
+func TestFindAdd(t *testing.T) {
+	srv := newTestServer(t)
+	c := dialTestServer(t, srv)
+
+	if err := c.FindAdd(Eq("artist", "Björk")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.SearchAdd(Eq("artist", "tool"), SortDesc("Title"), Position(0)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"Tool/Lateralus/01 The Grudge.flac",
+		"Tool/Lateralus/02 Eon Blue Apocalypse.flac",
+		"Björk/Homogenic/01 Hunter.flac",
+	}
+	if q := srv.Queue(); !reflect.DeepEqual(q, want) {
+		t.Fatalf("got queue %q, want %q", q, want)
+	}
+
+	if err := c.SearchAddPlaylist("Mix", Contains("title", "e"), Sort("Title"), Window(0, 2)); err != nil {
+		t.Fatal(err)
+	}
+
+	want = []string{"Tool/Lateralus/02 Eon Blue Apocalypse.flac", "Björk/Homogenic/01 Hunter.flac"}
+	if files, _ := srv.Playlist("Mix"); !reflect.DeepEqual(files, want) {
+		t.Fatalf("got playlist %q, want %q", files, want)
+	}
+
+	// Inserting into a stored playlist requires 0.24.
+	err := c.SearchAddPlaylist("Mix", Base("Tool"), Position(0))
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("got %v, want unsupported error", err)
+	}
+}
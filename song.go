@@ -4,42 +4,155 @@
 
 package mpd
 
+import (
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Song holds the metadata of a song in the database, the queue or a stored
+// playlist.
+//
+// Tags which may occur more than once, such as Artist, are available both
+// as a single value, which holds the first occurrence, and as a slice with
+// all of them. Tags without a field of their own can be read with Tag and
+// TagValues, and are listed in Extra.
 type Song struct {
-	File         string
-	Directory  	 string
-	Artist       string
-	AlbumArtist  string
-	Album        string
-	Title        string
-	Genre        string
-	LastModified string
-	MBArtistID   string
-	MBAArtistID  string
-	MBAlbumID    string
-	Date         int
-	Id           int
-	Pos          int
-	Track        int
-	Time         int
+	File      string `mpd:"file"`
+	Directory string `mpd:"directory"`
+
+	Artist           string   `mpd:"Artist"`
+	Artists          []string `mpd:"Artist"`
+	ArtistSort       string   `mpd:"ArtistSort"`
+	AlbumArtist      string   `mpd:"AlbumArtist"`
+	AlbumArtists     []string `mpd:"AlbumArtist"`
+	AlbumArtistSort  string   `mpd:"AlbumArtistSort"`
+	Album            string   `mpd:"Album"`
+	AlbumSort        string   `mpd:"AlbumSort"`
+	Title            string   `mpd:"Title"`
+	Name             string   `mpd:"Name"`
+	Genre            string   `mpd:"Genre"`
+	Genres           []string `mpd:"Genre"`
+	Composer         string   `mpd:"Composer"`
+	Composers        []string `mpd:"Composer"`
+	ComposerSort     string   `mpd:"ComposerSort"`
+	Performer        string   `mpd:"Performer"`
+	Performers       []string `mpd:"Performer"`
+	Conductor        string   `mpd:"Conductor"`
+	Work             string   `mpd:"Work"`
+	Movement         string   `mpd:"Movement"`
+	MovementNumber   string   `mpd:"MovementNumber"`
+	Grouping         string   `mpd:"Grouping"`
+	Comment          string   `mpd:"Comment"`
+	Comments         []string `mpd:"Comment"`
+	Label            string   `mpd:"Label"`
+	Date             string   `mpd:"Date"` // As tagged, eg: "2019" or "2019-05-03".
+	OriginalDate     string   `mpd:"OriginalDate"`
+	MBArtistID       string   `mpd:"MUSICBRAINZ_ARTISTID"`
+	MBAArtistID      string   `mpd:"MUSICBRAINZ_ALBUMARTISTID"`
+	MBAlbumID        string   `mpd:"MUSICBRAINZ_ALBUMID"`
+	MBTrackID        string   `mpd:"MUSICBRAINZ_TRACKID"`
+	MBReleaseTrackID string   `mpd:"MUSICBRAINZ_RELEASETRACKID"`
+	MBWorkID         string   `mpd:"MUSICBRAINZ_WORKID"`
+	Track            int      `mpd:"Track"` // Number of the track, without the total.
+	Disc             int      `mpd:"Disc"`  // Number of the disc, without the total.
+
+	LastModified string        `mpd:"Last-Modified"`
+	Format       AudioFormat   `mpd:"Format"`
+	Duration     time.Duration `mpd:"duration"`
+	Time         int           `mpd:"Time"`  // Duration in whole seconds.
+	Range        string        `mpd:"Range"` // Part of the file played, eg: "60.000-120.500".
+	Id           int           `mpd:"Id"`
+	Pos          int           `mpd:"Pos"`
+	Prio         int           `mpd:"Prio"`
+
+	Extra map[string][]string `mpd:"*"` // Fields not listed above.
+
+	fields Args
+}
+
+// Tag returns the first value of the named tag, or an empty string. Tag
+// names are not case sensitive.
+func (s *Song) Tag(name string) string {
+	if v := s.TagValues(name); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// TagValues returns all values of the named tag, in order. Tag names are not
+// case sensitive.
+func (s *Song) TagValues(name string) (v []string) {
+	for _, f := range s.fields {
+		if strings.EqualFold(f.Key, name) {
+			v = append(v, f.Value)
+		}
+	}
+	return
 }
 
+// readSong decodes a song. Values which can not be parsed are left zero.
 func readSong(a Args) *Song {
-	s := new(Song)
-	s.File = a.S("file")
-	s.Directory = a.S("directory")
-	s.Id = a.I("Id")
-	s.Pos = a.I("Pos")
-	s.Date = a.I("Date")
-	s.Artist = a.S("Artist")
-	s.AlbumArtist = a.S("AlbumArtist")
-	s.Album = a.S("Album")
-	s.Title = a.S("Title")
-	s.Genre = a.S("Genre")
-	s.Track = a.I("Track")
-	s.Time = a.I("Time")
-	s.LastModified = a.S("Last-Modified")
-	s.MBArtistID = a.S("MUSICBRAINZ_ARTISTID")
-	s.MBAArtistID = a.S("MUSICBRAINZ_ALBUMARTISTID")
-	s.MBAlbumID = a.S("MUSICBRAINZ_ALBUMID")
+	s := &Song{fields: a}
+	Unmarshal(a, s)
+
+	// Track and disc numbers may carry a total, as in "3/12".
+	s.Track = leadingInt(a.S("Track"))
+	s.Disc = leadingInt(a.S("Disc"))
+
+	// Older servers only send whole seconds.
+	if s.Duration == 0 && s.Time > 0 {
+		s.Duration = time.Duration(s.Time) * time.Second
+	}
+
 	return s
 }
+
+// readCurrentSong decodes the response to 'currentsong', which is empty if
+// there is no current song.
+func readCurrentSong(a Args) *Song {
+	if len(a) == 0 {
+		return nil
+	}
+	return readSong(a)
+}
+
+// leadingInt parses the number at the start of v. It returns 0 if there is
+// none.
+func leadingInt(v string) int {
+	v = strings.TrimSpace(v)
+
+	end := 0
+	for end < len(v) && v[end] >= '0' && v[end] <= '9' {
+		end++
+	}
+
+	n, _ := strconv.Atoi(v[:end])
+	return n
+}
+
+// readSongs decodes the entries of a list response.
+func readSongs(a []Args) []*Song {
+	list := make([]*Song, 0, len(a))
+	for _, m := range a {
+		list = append(list, readSong(m))
+	}
+	return list
+}
+
+// readSongSeq turns a sequence of response entries into a sequence of songs.
+func readSongSeq(seq iter.Seq2[Args, error]) iter.Seq2[*Song, error] {
+	return func(yield func(*Song, error) bool) {
+		for a, err := range seq {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			if !yield(readSong(a), nil) {
+				return
+			}
+		}
+	}
+}
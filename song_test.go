@@ -0,0 +1,97 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReadSong(t *testing.T) {
+	s := readSong(Args{
+		{"file", "a.flac"},
+		{"Artist", "Alice"},
+		{"Artist", "Bob"},
+		{"Title", "Duet"},
+		{"Date", "2019-05-03"},
+		{"Track", "3/12"},
+		{"Disc", "1"},
+		{"Composer", "Carol"},
+		{"MUSICBRAINZ_TRACKID", "a1b2"},
+		{"Mood", "happy"},
+		{"Time", "246"},
+		{"duration", "245.712"},
+		{"Format", "44100:f:2"},
+		{"Prio", "5"},
+	})
+
+	if s.Artist != "Alice" || !reflect.DeepEqual(s.Artists, []string{"Alice", "Bob"}) {
+		t.Errorf("got artist %q and artists %q", s.Artist, s.Artists)
+	}
+
+	if s.Date != "2019-05-03" || s.Track != 3 || s.Disc != 1 || s.Prio != 5 {
+		t.Errorf("got date %q, track %d, disc %d, prio %d", s.Date, s.Track, s.Disc, s.Prio)
+	}
+
+	if s.Composer != "Carol" || s.MBTrackID != "a1b2" {
+		t.Errorf("got composer %q and track id %q", s.Composer, s.MBTrackID)
+	}
+
+	if s.Duration != 245712*time.Millisecond || s.Time != 246 {
+		t.Errorf("got duration %v and time %d", s.Duration, s.Time)
+	}
+
+	if s.Format != (AudioFormat{SampleRate: 44100, Float: true, Channels: 2}) {
+		t.Errorf("got format %+v", s.Format)
+	}
+
+	if s.Tag("mood") != "happy" || len(s.TagValues("ARTIST")) != 2 {
+		t.Errorf("got mood %q and artists %q", s.Tag("mood"), s.TagValues("ARTIST"))
+	}
+
+	if !reflect.DeepEqual(s.Extra, map[string][]string{"Mood": {"happy"}}) {
+		t.Errorf("got extra %v", s.Extra)
+	}
+
+	if s = readSong(Args{{"file", "b.mp3"}, {"Time", "60"}}); s.Duration != time.Minute {
+		t.Errorf("got duration %v from time", s.Duration)
+	}
+}
+
+func TestAudioFormat(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want AudioFormat
+	}{
+		{"44100:24:2", AudioFormat{SampleRate: 44100, Bits: 24, Channels: 2}},
+		{"48000:f:6", AudioFormat{SampleRate: 48000, Float: true, Channels: 6}},
+		{"dsd64:2", AudioFormat{SampleRate: 2822400, DSD: true, Channels: 2}},
+		{"352800:dsd:2", AudioFormat{SampleRate: 2822400, DSD: true, Channels: 2}},
+		{"12345:dsd:1", AudioFormat{SampleRate: 98760, DSD: true, Channels: 1}},
+	} {
+		f, err := ParseAudioFormat(tt.in)
+		if err != nil || f != tt.want {
+			t.Errorf("ParseAudioFormat(%q) = %+v, %v; want %+v", tt.in, f, err, tt.want)
+		}
+	}
+
+	for in, want := range map[string]string{
+		"44100:16:2":   "44100:16:2",
+		"dsd128:2":     "dsd128:2",
+		"352800:dsd:2": "dsd64:2",
+		"12345:dsd:1":  "12345:dsd:1",
+	} {
+		if f, _ := ParseAudioFormat(in); f.String() != want {
+			t.Errorf("%q formats as %q, want %q", in, f.String(), want)
+		}
+	}
+
+	for _, in := range []string{"", "44100", "44100:x:2", "dsdx:2", "0:16:2", "44100:16:0"} {
+		if _, err := ParseAudioFormat(in); err == nil {
+			t.Errorf("ParseAudioFormat(%q) succeeded", in)
+		}
+	}
+}
@@ -5,23 +5,20 @@
 package mpd
 
 type Stats struct {
-	DbUpdate   int64
-	DbPlaytime int64
-	Albums     int
-	Artists    int
-	Songs      int
-	Playtime   int
-	Uptime     int
+	DbUpdate   int64               `mpd:"db_update"`
+	DbPlaytime int64               `mpd:"db_playtime"`
+	Albums     int                 `mpd:"albums"`
+	Artists    int                 `mpd:"artists"`
+	Songs      int                 `mpd:"songs"`
+	Playtime   int                 `mpd:"playtime"`
+	Uptime     int                 `mpd:"uptime"`
+	Extra      map[string][]string `mpd:"*"` // Fields not listed above.
 }
 
+// readStats decodes a stats response. Values which can not be parsed are
+// left zero.
 func readStats(a Args) *Stats {
 	s := new(Stats)
-	s.Albums = a.I("albums")
-	s.Artists = a.I("artists")
-	s.Songs = a.I("songs")
-	s.Playtime = a.I("playtime")
-	s.Uptime = a.I("uptime")
-	s.DbUpdate = a.I64("db_update")
-	s.DbPlaytime = a.I64("db_playtime")
+	Unmarshal(a, s)
 	return s
 }
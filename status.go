@@ -5,8 +5,10 @@
 package mpd
 
 import (
+	"errors"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type PlayState uint8
@@ -17,57 +19,109 @@ const (
 	Stopped
 )
 
+// UnmarshalText parses the 'state' field of a status response.
+func (p *PlayState) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "play":
+		*p = Playing
+	case "stop":
+		*p = Stopped
+	case "pause":
+		*p = Paused
+	default:
+		return errors.New("Invalid play state: " + string(b))
+	}
+	return nil
+}
+
+// SingleMode tells whether playback stops after the current song.
+type SingleMode uint8
+
+const (
+	SingleOff     SingleMode = iota
+	SingleOn                 // Stop after the current song, or repeat it in repeat mode.
+	SingleOneshot            // Like SingleOn, but switch back to SingleOff afterwards.
+)
+
+// String returns the mode the way the server writes it.
+func (m SingleMode) String() string {
+	switch m {
+	case SingleOn:
+		return "1"
+	case SingleOneshot:
+		return "oneshot"
+	}
+	return "0"
+}
+
+// UnmarshalText parses the 'single' field of a status response.
+func (m *SingleMode) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "0":
+		*m = SingleOff
+	case "1":
+		*m = SingleOn
+	case "oneshot":
+		*m = SingleOneshot
+	default:
+		return errors.New("Invalid single mode: " + string(b))
+	}
+	return nil
+}
+
 type Status struct {
-	Time           []int
-	Audio          []int
-	MixRampDB      float32
-	MixRampDelay   float32
-	Elapsed        float32
-	Playlist       int
-	PlaylistLength int
-	Song           int
-	SongId         int
-	NextSong       int
-	NextSongId     int
-	CrossFade      int
-	Bitrate        int
-	State          PlayState
-	Volume         byte
-	Single         bool
-	Repeat         bool
-	Random         bool
-	Consume        bool
+	Time               []int               // Deprecated: use Elapsed and Duration.
+	Audio              AudioFormat         `mpd:"audio"`
+	MixRampDB          float32             `mpd:"mixrampdb"`
+	MixRampDelay       float32             `mpd:"mixrampdelay"`
+	Elapsed            time.Duration       `mpd:"elapsed"`
+	Duration           time.Duration       `mpd:"duration"`
+	Playlist           int                 `mpd:"playlist"`
+	PlaylistLength     int                 `mpd:"playlistlength"`
+	Song               int                 `mpd:"song"`
+	SongId             int                 `mpd:"songid"`
+	NextSong           int                 `mpd:"nextsong"`
+	NextSongId         int                 `mpd:"nextsongid"`
+	CrossFade          int                 `mpd:"xfade"`
+	Bitrate            int                 `mpd:"bitrate"`
+	UpdatingDB         int                 `mpd:"updating_db"` // Id of the running update job, or 0.
+	Error              string              `mpd:"error"`       // Last error of the player, if any.
+	Partition          string              `mpd:"partition"`
+	LastLoadedPlaylist string              `mpd:"lastloadedplaylist"`
+	State              PlayState           `mpd:"state"`
+	Volume             int                 `mpd:"volume"` // -1 if the server has no mixer.
+	Single             SingleMode          `mpd:"single"`
+	Repeat             bool                `mpd:"repeat"`
+	Random             bool                `mpd:"random"`
+	Consume            bool                `mpd:"consume"`
+	Extra              map[string][]string `mpd:"*"` // Fields not listed above.
 }
 
+// readStatus decodes a status response. Values which can not be parsed are
+// left zero.
 func readStatus(a Args) *Status {
 	s := new(Status)
-	s.Volume = a.U8("volume")
-	s.Bitrate = a.I("bitrate")
-	s.PlaylistLength = a.I("playlistlength")
+	Unmarshal(a, s)
+
+	// Holds the elapsed and total time in whole seconds, separated by a
+	// colon. Older servers send nothing else.
 	s.Time = splitI(a.S("time"), ":")
-	s.Repeat = a.I("repeat") == 1
-	s.NextSong = a.I("nextsong")
-	s.MixRampDB = a.F32("mixrampdb")
-	s.Playlist = a.I("playlist")
-	s.Song = a.I("song")
-	s.NextSongId = a.I("nextsongid")
-	s.MixRampDelay = a.F32("mixrampdelay")
-	s.Single = a.I("single") == 1
-	s.CrossFade = a.I("xfade")
-	s.Elapsed = a.F32("elapsed")
-	s.SongId = a.I("songid")
-	s.Random = a.I("random") == 1
-	s.Consume = a.I("consume") == 1
-	s.Audio = splitI(a.S("audio"), ":")
-
-	switch a.S("state") {
-	case "play":
-		s.State = Playing
-	case "stop":
-		s.State = Stopped
-	case "pause":
-		s.State = Paused
+	delete(s.Extra, "time")
+
+	// Servers without a mixer send -1 or leave the field out.
+	if !a.Has("volume") {
+		s.Volume = -1
 	}
+
+	if len(s.Time) == 2 {
+		if !a.Has("elapsed") {
+			s.Elapsed = time.Duration(s.Time[0]) * time.Second
+		}
+		if !a.Has("duration") {
+			s.Duration = time.Duration(s.Time[1]) * time.Second
+		}
+	}
+
 	return s
 }
 
@@ -75,6 +129,10 @@ func splitI(v string, delim string) []int {
 	var list []int
 	var el []string
 
+	if len(v) == 0 {
+		return nil
+	}
+
 	if el = strings.Split(v, delim); len(el) == 0 {
 		return nil
 	}
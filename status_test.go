@@ -0,0 +1,87 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jteeuwen/go-pkg-mpd/mpdtest"
+)
+
+func TestReadStatus(t *testing.T) {
+	s := readStatus(Args{
+		{"volume", "80"},
+		{"single", "oneshot"},
+		{"state", "play"},
+		{"time", "12:246"},
+		{"elapsed", "12.345"},
+		{"duration", "245.712"},
+		{"audio", "dsd64:2"},
+		{"updating_db", "7"},
+		{"partition", "default"},
+		{"lastloadedplaylist", "Favourites"},
+		{"error", "Failed to open audio output"},
+	})
+
+	if s.Single != SingleOneshot || s.State != Playing || s.Volume != 80 {
+		t.Errorf("got single %v, state %v, volume %d", s.Single, s.State, s.Volume)
+	}
+
+	if s.Elapsed != 12345*time.Millisecond || s.Duration != 245712*time.Millisecond {
+		t.Errorf("got elapsed %v and duration %v", s.Elapsed, s.Duration)
+	}
+
+	if s.Audio != (AudioFormat{SampleRate: 2822400, DSD: true, Channels: 2}) {
+		t.Errorf("got audio %+v", s.Audio)
+	}
+
+	if s.UpdatingDB != 7 || s.Partition != "default" || s.LastLoadedPlaylist != "Favourites" {
+		t.Errorf("got update %d, partition %q, playlist %q", s.UpdatingDB, s.Partition, s.LastLoadedPlaylist)
+	}
+
+	if s.Error != "Failed to open audio output" {
+		t.Errorf("got error %q", s.Error)
+	}
+
+	// Older servers only send whole seconds.
+	if s = readStatus(Args{{"time", "3:60"}}); s.Elapsed != 3*time.Second || s.Duration != time.Minute {
+		t.Errorf("got elapsed %v and duration %v from time", s.Elapsed, s.Duration)
+	}
+	// Without a mixer there is no volume.
+	if s = readStatus(Args{{"volume", "-1"}}); s.Volume != -1 {
+		t.Errorf("got volume %d, want -1", s.Volume)
+	}
+
+	if s = readStatus(Args{{"state", "stop"}}); s.Volume != -1 {
+		t.Errorf("got volume %d without a volume field, want -1", s.Volume)
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	srv := newTestServer(t)
+	srv.Handle("status", func(r *mpdtest.Response, args []string) error {
+		r.Add("state", "stop")
+		r.Add("error", "problems opening audio device")
+		r.Add("volume", "50")
+		return nil
+	})
+
+	c := dialTestServer(t, srv)
+
+	s, err := c.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s.Error != "problems opening audio device" || s.Volume != 50 {
+		t.Fatalf("unexpected status: %+v", s)
+	}
+
+	// The connection must still be in sync.
+	if err = c.Single(SingleOneshot); err != nil {
+		t.Fatal(err)
+	}
+}
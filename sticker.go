@@ -0,0 +1,318 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StickerType is the kind of object a sticker is attached to.
+type StickerType string
+
+const (
+	StickerSong     StickerType = "song"
+	StickerPlaylist StickerType = "playlist" // Requires protocol version 0.24.
+)
+
+// requireSticker checks that the server supports stickers of type typ.
+func (c *Client) requireSticker(typ StickerType, cmd string) error {
+	if typ == StickerPlaylist {
+		return c.require("sticker "+cmd, versionStickerPlaylist)
+	}
+	return nil
+}
+
+// Sticker is a name/value pair attached to an object in the database.
+type Sticker struct {
+	Name  string
+	Value string
+}
+
+// StickerMatch is a result of StickerFind: a sticker and the object it is
+// attached to.
+type StickerMatch struct {
+	URI string
+	Sticker
+}
+
+// readSticker parses a 'sticker: name=value' field.
+func readSticker(v string) Sticker {
+	name, value, _ := strings.Cut(v, "=")
+	return Sticker{name, value}
+}
+
+// StickerGet returns the value of the sticker called name on the object at
+// uri. A missing sticker results in an error matching ErrNoExist.
+func (c *Client) StickerGet(typ StickerType, uri, name string) (value string, err error) {
+	var a Args
+
+	if err = c.requireSticker(typ, "get"); err != nil {
+		return
+	}
+
+	if a, err = c.request("sticker", "get", typ, uri, name); err != nil {
+		return
+	}
+
+	return readSticker(a.S("sticker")).Value, nil
+}
+
+// StickerSet sets the sticker called name on the object at uri.
+func (c *Client) StickerSet(typ StickerType, uri, name, value string) (err error) {
+	if err = c.requireSticker(typ, "set"); err != nil {
+		return
+	}
+
+	_, err = c.request("sticker", "set", typ, uri, name, value)
+	return
+}
+
+// StickerInc adds n to the numeric sticker called name on the object at
+// uri. A missing sticker counts as zero. It requires protocol version 0.24.
+func (c *Client) StickerInc(typ StickerType, uri, name string, n int) (err error) {
+	if err = c.require("sticker inc", versionStickerInc); err != nil {
+		return
+	}
+
+	_, err = c.request("sticker", "inc", typ, uri, name, n)
+	return
+}
+
+// StickerDec subtracts n from the numeric sticker called name on the object
+// at uri. It requires protocol version 0.24.
+func (c *Client) StickerDec(typ StickerType, uri, name string, n int) (err error) {
+	if err = c.require("sticker dec", versionStickerInc); err != nil {
+		return
+	}
+
+	_, err = c.request("sticker", "dec", typ, uri, name, n)
+	return
+}
+
+// StickerDelete deletes the sticker called name from the object at uri. An
+// empty name deletes all of its stickers.
+func (c *Client) StickerDelete(typ StickerType, uri, name string) (err error) {
+	if err = c.requireSticker(typ, "delete"); err != nil {
+		return
+	}
+
+	args := []interface{}{"delete", typ, uri}
+	if len(name) > 0 {
+		args = append(args, name)
+	}
+
+	_, err = c.request("sticker", args...)
+	return
+}
+
+// StickerList returns all stickers on the object at uri.
+func (c *Client) StickerList(typ StickerType, uri string) (list []Sticker, err error) {
+	var a Args
+
+	if err = c.requireSticker(typ, "list"); err != nil {
+		return
+	}
+
+	if a, err = c.request("sticker", "list", typ, uri); err != nil {
+		return
+	}
+
+	for _, v := range a.Values("sticker") {
+		list = append(list, readSticker(v))
+	}
+	return
+}
+
+// StickerFind returns the objects at or below uri which have a sticker
+// called name, along with its value. An empty uri searches the whole
+// database.
+//
+// The result can be ordered with Sort or SortDesc, by "uri", "value" or
+// "value_int", and limited with Window. Options require protocol version
+// 0.24.
+func (c *Client) StickerFind(typ StickerType, uri, name string, opts ...QueryOption) ([]StickerMatch, error) {
+	if err := c.requireSticker(typ, "find"); err != nil {
+		return nil, err
+	}
+
+	return c.stickerFind([]interface{}{"find", typ, uri, name}, opts)
+}
+
+// StickerFindValue is like StickerFind, but only returns stickers whose
+// value compares to value as given by op. The operators "=", "<" and ">"
+// compare strings. Since protocol version 0.24, "eq", "lt" and "gt" compare
+// integers, and "contains" and "starts_with" are supported.
+func (c *Client) StickerFindValue(typ StickerType, uri, name, op, value string, opts ...QueryOption) ([]StickerMatch, error) {
+	if err := c.requireSticker(typ, "find"); err != nil {
+		return nil, err
+	}
+
+	switch op {
+	case "eq", "lt", "gt", "contains", "starts_with":
+		if err := c.require("sticker find", versionStickerFind); err != nil {
+			return nil, err
+		}
+	}
+
+	return c.stickerFind([]interface{}{"find", typ, uri, name, op, value}, opts)
+}
+
+// stickerFind sends 'sticker find' with the given arguments and options.
+func (c *Client) stickerFind(args []interface{}, opts []QueryOption) (list []StickerMatch, err error) {
+	var a Args
+
+	for _, name := range queryOptionOrder {
+		for _, o := range opts {
+			if o.name == name {
+				args = append(args, o.name, o.value)
+				break
+			}
+		}
+	}
+
+	if len(opts) > 0 {
+		if err = c.require("sticker find", versionStickerFind); err != nil {
+			return
+		}
+	}
+
+	if a, err = c.request("sticker", args...); err != nil {
+		return
+	}
+
+	for _, f := range a {
+		switch {
+		case f.Key == "file" || f.Key == "playlist" || f.Key == "uri":
+			list = append(list, StickerMatch{URI: f.Value})
+		case f.Key == "sticker" && len(list) > 0:
+			list[len(list)-1].Sticker = readSticker(f.Value)
+		}
+	}
+	return
+}
+
+// SongStickers reads and writes stickers commonly kept for songs: ratings,
+// play counts and the time songs were last played. The sticker names start
+// with a namespace, so several programs can keep their own.
+type SongStickers struct {
+	c         *Client
+	namespace string
+}
+
+// SongStickers returns a SongStickers for the given namespace. It is put in
+// front of the sticker names "rating", "playcount" and "lastplayed" as it
+// is, so it usually ends in a separator, eg: "myplayer.". An empty
+// namespace uses the plain names.
+func (c *Client) SongStickers(namespace string) *SongStickers {
+	return &SongStickers{c: c, namespace: namespace}
+}
+
+// Names of the stickers kept by SongStickers.
+const (
+	stickerRating     = "rating"
+	stickerPlayCount  = "playcount"
+	stickerLastPlayed = "lastplayed"
+)
+
+// Rating returns the rating of the song at uri, or 0 if it has none.
+func (s *SongStickers) Rating(uri string) (int, error) {
+	return s.getInt(uri, stickerRating)
+}
+
+// SetRating sets the rating of the song at uri. The scale is up to the
+// caller; a rating of 0 deletes the sticker.
+func (s *SongStickers) SetRating(uri string, rating int) error {
+	if rating == 0 {
+		return s.delete(uri, stickerRating)
+	}
+	return s.c.StickerSet(StickerSong, uri, s.namespace+stickerRating, strconv.Itoa(rating))
+}
+
+// PlayCount returns how often the song at uri has been played.
+func (s *SongStickers) PlayCount(uri string) (int, error) {
+	return s.getInt(uri, stickerPlayCount)
+}
+
+// LastPlayed returns when the song at uri was last played, or the zero time
+// if it never was.
+func (s *SongStickers) LastPlayed(uri string) (t time.Time, err error) {
+	var n int64
+
+	v, err := s.get(uri, stickerLastPlayed)
+	if err != nil || len(v) == 0 {
+		return
+	}
+
+	if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+		return
+	}
+
+	return time.Unix(n, 0), nil
+}
+
+// Played records that the song at uri was played at t: it increments the
+// play count and sets the last played time. Servers older than protocol
+// version 0.24 can not increment stickers themselves; the play count is
+// then read and written back, which is not atomic.
+func (s *SongStickers) Played(uri string, t time.Time) (err error) {
+	name := s.namespace + stickerPlayCount
+
+	if s.c.ServerVersion().AtLeast(versionStickerInc) {
+		err = s.c.StickerInc(StickerSong, uri, name, 1)
+	} else {
+		var n int
+		if n, err = s.PlayCount(uri); err == nil {
+			err = s.c.StickerSet(StickerSong, uri, name, strconv.Itoa(n+1))
+		}
+	}
+
+	if err != nil {
+		return
+	}
+
+	return s.c.StickerSet(StickerSong, uri, s.namespace+stickerLastPlayed, strconv.FormatInt(t.Unix(), 10))
+}
+
+// Reset deletes the rating, play count and last played time of the song at
+// uri.
+func (s *SongStickers) Reset(uri string) error {
+	for _, name := range []string{stickerRating, stickerPlayCount, stickerLastPlayed} {
+		if err := s.delete(uri, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// get returns the value of a sticker, or an empty string if it is missing.
+func (s *SongStickers) get(uri, name string) (string, error) {
+	v, err := s.c.StickerGet(StickerSong, uri, s.namespace+name)
+	if errors.Is(err, ErrNoExist) {
+		return "", nil
+	}
+	return v, err
+}
+
+// getInt is like get, but parses the value as an integer. A missing sticker
+// yields 0.
+func (s *SongStickers) getInt(uri, name string) (int, error) {
+	v, err := s.get(uri, name)
+	if err != nil || len(v) == 0 {
+		return 0, err
+	}
+	return strconv.Atoi(v)
+}
+
+// delete deletes a sticker. A missing sticker is not an error.
+func (s *SongStickers) delete(uri, name string) error {
+	err := s.c.StickerDelete(StickerSong, uri, s.namespace+name)
+	if errors.Is(err, ErrNoExist) {
+		return nil
+	}
+	return err
+}
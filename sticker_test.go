@@ -0,0 +1,176 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	grudge = "Tool/Lateralus/01 The Grudge.flac"
+	eon    = "Tool/Lateralus/02 Eon Blue Apocalypse.flac"
+	hunter = "Björk/Homogenic/01 Hunter.flac"
+)
+
+func TestStickers(t *testing.T) {
+	srv := newTestServer(t)
+	c := dialTestServer(t, srv)
+
+	if err := c.StickerSet(StickerSong, grudge, "mood", "a=b"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := c.StickerGet(StickerSong, grudge, "mood"); err != nil || v != "a=b" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+
+	if _, err := c.StickerGet(StickerSong, grudge, "missing"); !errors.Is(err, ErrNoExist) {
+		t.Fatalf("got %v, want ErrNoExist", err)
+	}
+
+	c.StickerSet(StickerSong, grudge, "count", "3")
+	c.StickerSet(StickerSong, eon, "count", "10")
+	c.StickerSet(StickerSong, hunter, "count", "7")
+
+	list, err := c.StickerList(StickerSong, grudge)
+	if err != nil {
+		t.Fatal(err)
+	} else if want := []Sticker{{"count", "3"}, {"mood", "a=b"}}; !reflect.DeepEqual(list, want) {
+		t.Fatalf("got %+v, want %+v", list, want)
+	}
+
+	found, err := c.StickerFind(StickerSong, "Tool", "count")
+	if err != nil {
+		t.Fatal(err)
+	} else if len(found) != 2 || found[0].URI != grudge || found[1].Value != "10" {
+		t.Fatalf("unexpected result: %+v", found)
+	}
+
+	if found, err = c.StickerFindValue(StickerSong, "", "count", "=", "7"); err != nil {
+		t.Fatal(err)
+	} else if len(found) != 1 || found[0].URI != hunter {
+		t.Fatalf("unexpected result: %+v", found)
+	}
+
+	// Sorting and integer comparisons require 0.24.
+	if _, err = c.StickerFind(StickerSong, "", "count", SortDesc("value_int")); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("got %v, want unsupported error", err)
+	}
+
+	if err = c.StickerDelete(StickerSong, grudge, ""); err != nil {
+		t.Fatal(err)
+	} else if list, _ = c.StickerList(StickerSong, grudge); len(list) != 0 {
+		t.Fatalf("stickers left after delete: %+v", list)
+	}
+
+	srv.SetVersion("0.24.0")
+	c = dialTestServer(t, srv)
+
+	if found, err = c.StickerFindValue(StickerSong, "", "count", "gt", "5", SortDesc("value_int"), Window(0, 1)); err != nil {
+		t.Fatal(err)
+	} else if len(found) != 1 || found[0].URI != eon {
+		t.Fatalf("unexpected result: %+v", found)
+	}
+
+	if err = c.StickerDec(StickerSong, eon, "count", 4); err != nil {
+		t.Fatal(err)
+	} else if v, _ := srv.Sticker("song", eon, "count"); v != "6" {
+		t.Fatalf("got count %s after dec, want 6", v)
+	}
+}
+
+func TestPlaylistStickers(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetPlaylist("Favourites", grudge, hunter)
+
+	c := dialTestServer(t, srv)
+
+	// Stickers on playlists require 0.24, so none of these reach the server.
+	for name, err := range map[string]error{
+		"get":    func() error { _, err := c.StickerGet(StickerPlaylist, "Favourites", "mood"); return err }(),
+		"set":    c.StickerSet(StickerPlaylist, "Favourites", "mood", "calm"),
+		"delete": c.StickerDelete(StickerPlaylist, "Favourites", ""),
+		"list":   func() error { _, err := c.StickerList(StickerPlaylist, "Favourites"); return err }(),
+		"find":   func() error { _, err := c.StickerFind(StickerPlaylist, "", "mood"); return err }(),
+		"value":  func() error { _, err := c.StickerFindValue(StickerPlaylist, "", "mood", "=", "calm"); return err }(),
+	} {
+		var ue *UnsupportedError
+		if !errors.As(err, &ue) || ue.Need != versionStickerPlaylist {
+			t.Errorf("%s: got %v, want unsupported error", name, err)
+		}
+	}
+
+	srv.SetVersion("0.24.0")
+	c = dialTestServer(t, srv)
+
+	if err := c.StickerSet(StickerPlaylist, "Favourites", "mood", "calm"); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := c.StickerGet(StickerPlaylist, "Favourites", "mood"); err != nil || v != "calm" {
+		t.Fatalf("got %q, %v", v, err)
+	}
+
+	found, err := c.StickerFind(StickerPlaylist, "", "mood")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []StickerMatch{{"Favourites", Sticker{"mood", "calm"}}}
+	if !reflect.DeepEqual(found, want) {
+		t.Fatalf("got %+v, want %+v", found, want)
+	}
+}
+
+func TestSongStickers(t *testing.T) {
+	for _, version := range []string{"0.23.5", "0.24.0"} {
+		srv := newTestServer(t)
+		srv.SetVersion(version)
+
+		s := dialTestServer(t, srv).SongStickers("test.")
+
+		if n, err := s.PlayCount(grudge); err != nil || n != 0 {
+			t.Fatalf("%s: got play count %d, %v", version, n, err)
+		}
+
+		when := time.Unix(1700000000, 0)
+		for i := 0; i < 2; i++ {
+			if err := s.Played(grudge, when); err != nil {
+				t.Fatalf("%s: %v", version, err)
+			}
+		}
+
+		if n, err := s.PlayCount(grudge); err != nil || n != 2 {
+			t.Fatalf("%s: got play count %d, %v", version, n, err)
+		}
+
+		if v, _ := srv.Sticker("song", grudge, "test.playcount"); v != "2" {
+			t.Fatalf("%s: sticker holds %q", version, v)
+		}
+
+		if last, err := s.LastPlayed(grudge); err != nil || !last.Equal(when) {
+			t.Fatalf("%s: got last played %v, %v", version, last, err)
+		}
+
+		if err := s.SetRating(grudge, 8); err != nil {
+			t.Fatal(err)
+		} else if r, err := s.Rating(grudge); err != nil || r != 8 {
+			t.Fatalf("%s: got rating %d, %v", version, r, err)
+		}
+
+		if err := s.Reset(grudge); err != nil {
+			t.Fatal(err)
+		} else if r, _ := s.Rating(grudge); r != 0 {
+			t.Fatalf("%s: got rating %d after reset", version, r)
+		}
+
+		if err := s.Reset(grudge); err != nil {
+			t.Fatalf("%s: reset without stickers: %v", version, err)
+		}
+	}
+}
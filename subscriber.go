@@ -0,0 +1,276 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Number of messages buffered per channel by a Subscriber.
+const subscriberBuffer = 16
+
+// Subscriber receives client to client messages on a dedicated connection
+// to the server. It waits for changes in MessageSystem, reads the new
+// messages and delivers each on the Go channel of its MPD channel.
+//
+// When the connection is lost, the subscriber reports the error on its
+// Error channel, reconnects and subscribes to its channels again. Messages
+// sent while it was disconnected are lost.
+//
+// The Error channel must be drained until it is closed by Close.
+type Subscriber struct {
+	Error <-chan error
+
+	error    chan error
+	ctx      context.Context
+	cancel   context.CancelFunc
+	exited   chan struct{}
+	network  string
+	address  string
+	password string
+
+	mu       sync.Mutex
+	c        *Client // Nil while disconnected.
+	channels map[string]*subscription
+}
+
+// subscription delivers the messages of a single channel.
+type subscription struct {
+	ch     chan Message
+	done   chan struct{} // Closed by Unsubscribe.
+	mu     sync.Mutex    // Held while sending on ch.
+	closed bool
+}
+
+// NewSubscriber connects to the MPD server at the given address. See
+// DialNetwork for the meaning of the arguments. Channels are added with
+// Subscribe.
+func NewSubscriber(network, address, password string) (s *Subscriber, err error) {
+	s = new(Subscriber)
+	s.error = make(chan error)
+	s.Error = s.error
+	s.exited = make(chan struct{})
+	s.network = network
+	s.address = address
+	s.password = password
+	s.channels = make(map[string]*subscription)
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	if s.c, err = DialContext(s.ctx, network, address, password); err != nil {
+		s.cancel()
+		return nil, err
+	}
+
+	go s.run()
+	return
+}
+
+// Subscribe subscribes to channel and returns the Go channel its messages
+// are delivered on. The Go channel is closed by Unsubscribe and Close.
+// Messages which are not received in time are buffered up to a limit;
+// beyond it, the delivery of further messages waits.
+func (s *Subscriber) Subscribe(channel string) (<-chan Message, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx.Err() != nil {
+		return nil, errors.New("Subscriber is closed.")
+	}
+
+	if _, ok := s.channels[channel]; ok {
+		return nil, &Error{Code: ErrExist, Command: "subscribe", Message: "already subscribed to this channel"}
+	}
+
+	// While disconnected, the subscription is made after reconnecting.
+	if s.c != nil {
+		if err := s.c.Subscribe(channel); err != nil {
+			return nil, err
+		}
+	}
+
+	sub := &subscription{
+		ch:   make(chan Message, subscriberBuffer),
+		done: make(chan struct{}),
+	}
+
+	s.channels[channel] = sub
+	return sub.ch, nil
+}
+
+// Unsubscribe unsubscribes from channel and closes its Go channel.
+func (s *Subscriber) Unsubscribe(channel string) (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub, ok := s.channels[channel]
+	if !ok {
+		return &Error{Code: ErrNoExist, Command: "unsubscribe", Message: "not subscribed to this channel"}
+	}
+
+	if s.c != nil {
+		err = s.c.Unsubscribe(channel)
+	}
+
+	delete(s.channels, channel)
+	sub.close()
+	return
+}
+
+// Close stops the subscriber, closes its connection and all Go channels.
+// The Error channel is closed before Close returns.
+func (s *Subscriber) Close() error {
+	s.cancel()
+	<-s.exited
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, sub := range s.channels {
+		delete(s.channels, name)
+		sub.close()
+	}
+	return nil
+}
+
+// close stops delivery and closes the Go channel. A pending delivery is
+// abandoned.
+func (sub *subscription) close() {
+	close(sub.done)
+
+	sub.mu.Lock()
+	sub.closed = true
+	close(sub.ch)
+	sub.mu.Unlock()
+}
+
+// deliver sends m on the Go channel unless the subscription or ctx ends
+// first.
+func (sub *subscription) deliver(ctx context.Context, m Message) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	if sub.closed {
+		return
+	}
+
+	select {
+	case sub.ch <- m:
+	case <-sub.done:
+	case <-ctx.Done():
+	}
+}
+
+func (s *Subscriber) run() {
+	defer close(s.exited)
+	defer close(s.error)
+
+	var failures int
+
+	for {
+		s.mu.Lock()
+		c := s.c
+		s.mu.Unlock()
+
+		if c == nil {
+			if !s.reconnect(&failures) {
+				return
+			}
+			continue
+		}
+
+		_, err := c.WithContext(s.ctx).IdleSubSystems(MessageSystem)
+
+		var list []Message
+		if err == nil {
+			list, err = c.WithContext(s.ctx).ReadMessages()
+		}
+
+		if s.ctx.Err() != nil {
+			c.Close()
+			return
+		}
+
+		if err != nil {
+			s.disconnect(c)
+
+			failures++
+			if !s.sendError(err) {
+				return
+			}
+			continue
+		}
+
+		failures = 0
+
+		for _, m := range list {
+			s.mu.Lock()
+			sub := s.channels[m.Channel]
+			s.mu.Unlock()
+
+			if sub != nil {
+				sub.deliver(s.ctx, m)
+			}
+		}
+	}
+}
+
+// disconnect closes c and marks the subscriber as disconnected.
+func (s *Subscriber) disconnect(c *Client) {
+	s.mu.Lock()
+	s.c = nil
+	s.mu.Unlock()
+
+	c.Close()
+}
+
+// reconnect dials the server and subscribes to all channels again, until it
+// succeeds or the subscriber is closed. failures holds the number of
+// consecutive failures so far and determines the delay before the next
+// attempt. It reports false if the subscriber was closed.
+func (s *Subscriber) reconnect(failures *int) bool {
+	if !waitBackoff(s.ctx, *failures) {
+		return false
+	}
+
+	c, err := DialContext(s.ctx, s.network, s.address, s.password)
+	if err == nil {
+		s.mu.Lock()
+		for name := range s.channels {
+			if err = c.Subscribe(name); err != nil {
+				break
+			}
+		}
+
+		if err == nil {
+			s.c = c
+		}
+		s.mu.Unlock()
+
+		if err == nil {
+			return true
+		}
+		c.Close()
+	}
+
+	if s.ctx.Err() != nil {
+		return false
+	}
+
+	*failures++
+	return s.sendError(err)
+}
+
+// sendError delivers err on the Error channel. It reports false if the
+// subscriber was closed instead.
+func (s *Subscriber) sendError(err error) bool {
+	select {
+	case s.error <- err:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
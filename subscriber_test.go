@@ -0,0 +1,130 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMessages(t *testing.T) {
+	srv := newTestServer(t)
+	a := dialTestServer(t, srv)
+	b := dialTestServer(t, srv)
+
+	if err := a.SendMessage("kiosk", "hello"); !errors.Is(err, ErrNoExist) {
+		t.Fatalf("got %v, want ErrNoExist", err)
+	}
+
+	if err := a.Subscribe("kiosk"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := a.Subscribe("kiosk"); !errors.Is(err, ErrExist) {
+		t.Fatalf("got %v, want ErrExist", err)
+	}
+
+	if err := a.Subscribe("bad channel"); !errors.Is(err, ErrArg) {
+		t.Fatalf("got %v, want ErrArg", err)
+	}
+
+	if list, err := b.Channels(); err != nil || !reflect.DeepEqual(list, []string{"kiosk"}) {
+		t.Fatalf("got channels %q, %v", list, err)
+	}
+
+	b.SendMessage("kiosk", "one")
+	b.SendMessage("kiosk", `two "quoted"`)
+
+	list, err := a.ReadMessages()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Message{{"kiosk", "one"}, {"kiosk", `two "quoted"`}}
+	if !reflect.DeepEqual(list, want) {
+		t.Fatalf("got %+v, want %+v", list, want)
+	}
+
+	if err = a.Unsubscribe("kiosk"); err != nil {
+		t.Fatal(err)
+	}
+
+	if list, _ := b.Channels(); len(list) != 0 {
+		t.Fatalf("got channels %q after unsubscribe", list)
+	}
+}
+
+func TestSubscriber(t *testing.T) {
+	srv := newTestServer(t)
+	c := dialTestServer(t, srv)
+
+	s, err := NewSubscriber("tcp", srv.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		for err := range s.Error {
+			t.Error(err)
+		}
+	}()
+
+	red, err := s.Subscribe("red")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blue, err := s.Subscribe("blue")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receive := func(ch <-chan Message) Message {
+		t.Helper()
+		select {
+		case m := <-ch:
+			return m
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a message")
+			return Message{}
+		}
+	}
+
+	c.SendMessage("blue", "b1")
+	c.SendMessage("red", "r1")
+	c.SendMessage("blue", "b2")
+
+	if m := receive(blue); m != (Message{"blue", "b1"}) {
+		t.Fatalf("got %+v", m)
+	}
+
+	if m := receive(red); m != (Message{"red", "r1"}) {
+		t.Fatalf("got %+v", m)
+	}
+
+	if m := receive(blue); m.Text != "b2" {
+		t.Fatalf("got %+v", m)
+	}
+
+	if err = s.Unsubscribe("red"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := <-red; ok {
+		t.Fatal("channel still open after Unsubscribe")
+	}
+
+	if err = c.SendMessage("red", "lost"); !errors.Is(err, ErrNoExist) {
+		t.Fatalf("got %v, want ErrNoExist", err)
+	}
+
+	s.Close()
+
+	if _, ok := <-blue; ok {
+		t.Fatal("channel still open after Close")
+	}
+}
@@ -0,0 +1,238 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Unmarshal stores the fields of a into the struct pointed to by v. Struct
+// fields name the key they are read from with an 'mpd' tag:
+//
+//     type Neighbor struct {
+//         URI  string `mpd:"neighbor"`
+//         Name string `mpd:"name"`
+//     }
+//
+// Fields without a tag, or with the tag "-", are left alone, as are fields
+// whose key does not occur in a. A field of type map[string][]string tagged
+// "*" receives all fields of a whose key is not claimed by another field.
+//
+// Strings, booleans, integer and floating point numbers are supported, as
+// are pointers to them and types implementing encoding.TextUnmarshaler. A
+// time.Duration is read as a number of seconds, which may have a fraction.
+// A time.Time is read as an RFC 3339 timestamp or as seconds since the Unix
+// epoch. A slice receives the values of all fields with its key; any other
+// field receives the first one.
+//
+// A value which can not be parsed as the type of its field results in an
+// error; the remaining fields are still filled in.
+func Unmarshal(a Args, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("Unmarshal requires a non-nil pointer to a struct.")
+	}
+
+	rv = rv.Elem()
+	info := structInfoOf(rv.Type())
+
+	var firstErr error
+	fail := func(f *fieldInfo, value string, err error) {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("Cannot decode %q into field %s: %v", value, f.name, err)
+		}
+	}
+
+	for i := range info.fields {
+		f := &info.fields[i]
+		fv := rv.Field(f.index)
+
+		if fv.Kind() == reflect.Slice && !isText(fv) {
+			values := a.Values(f.key)
+			if values == nil {
+				continue
+			}
+
+			s := reflect.MakeSlice(fv.Type(), len(values), len(values))
+			for j, value := range values {
+				if err := setField(s.Index(j), value); err != nil {
+					fail(f, value, err)
+				}
+			}
+
+			fv.Set(s)
+			continue
+		}
+
+		if value, ok := a.Get(f.key); ok {
+			if err := setField(fv, value); err != nil {
+				fail(f, value, err)
+			}
+		}
+	}
+
+	if info.rest >= 0 {
+		rest := make(map[string][]string)
+		for _, field := range a {
+			if _, ok := info.keys[field.Key]; !ok {
+				rest[field.Key] = append(rest[field.Key], field.Value)
+			}
+		}
+		rv.Field(info.rest).Set(reflect.ValueOf(rest))
+	}
+
+	return firstErr
+}
+
+// UnmarshalList decodes every entry of list into a new T, as by Unmarshal.
+// It is meant for the entries returned by Client.CommandSplit.
+func UnmarshalList[T any](list []Args) (out []T, err error) {
+	out = make([]T, len(list))
+
+	for i, a := range list {
+		if e := Unmarshal(a, &out[i]); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return
+}
+
+// structInfo describes the tagged fields of a struct type.
+type structInfo struct {
+	fields []fieldInfo
+	keys   map[string]struct{} // Keys claimed by fields.
+	rest   int                 // Index of the catch-all field, or -1.
+}
+
+type fieldInfo struct {
+	index int
+	name  string
+	key   string
+}
+
+var (
+	structInfoCache sync.Map // map[reflect.Type]*structInfo
+
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	restType     = reflect.TypeOf(map[string][]string(nil))
+	textType     = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// structInfoOf returns the description of a struct type, which is built
+// once per type.
+func structInfoOf(t reflect.Type) *structInfo {
+	if info, ok := structInfoCache.Load(t); ok {
+		return info.(*structInfo)
+	}
+
+	info := &structInfo{keys: make(map[string]struct{}), rest: -1}
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := sf.Tag.Get("mpd")
+		if len(key) == 0 || key == "-" || !sf.IsExported() {
+			continue
+		}
+
+		if key == "*" {
+			if sf.Type == restType {
+				info.rest = i
+			}
+			continue
+		}
+
+		info.fields = append(info.fields, fieldInfo{index: i, name: sf.Name, key: key})
+		info.keys[key] = struct{}{}
+	}
+
+	v, _ := structInfoCache.LoadOrStore(t, info)
+	return v.(*structInfo)
+}
+
+// isText reports whether f implements encoding.TextUnmarshaler.
+func isText(f reflect.Value) bool {
+	return reflect.PointerTo(f.Type()).Implements(textType)
+}
+
+// setField parses s into the value of a struct field.
+func setField(f reflect.Value, s string) (err error) {
+	switch f.Type() {
+	case durationType:
+		var n float64
+		if n, err = strconv.ParseFloat(s, 64); err == nil {
+			f.SetInt(int64(math.Round(n * float64(time.Second))))
+		}
+		return
+
+	case timeType:
+		var t time.Time
+		if t, err = parseTime(s); err == nil {
+			f.Set(reflect.ValueOf(t))
+		}
+		return
+	}
+
+	if isText(f) {
+		return f.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			f.SetBool(b)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, f.Type().Bits()); err == nil {
+			f.SetInt(n)
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, f.Type().Bits()); err == nil {
+			f.SetUint(n)
+		}
+
+	case reflect.Float32, reflect.Float64:
+		var n float64
+		if n, err = strconv.ParseFloat(s, f.Type().Bits()); err == nil {
+			f.SetFloat(n)
+		}
+
+	case reflect.Pointer:
+		p := reflect.New(f.Type().Elem())
+		if err = setField(p.Elem(), s); err == nil {
+			f.Set(p)
+		}
+
+	default:
+		err = fmt.Errorf("unsupported type %s", f.Type())
+	}
+
+	return
+}
+
+// parseTime parses an RFC 3339 timestamp, as sent in 'Last-Modified', or a
+// number of seconds since the Unix epoch, as sent in 'db_update'.
+func parseTime(s string) (time.Time, error) {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
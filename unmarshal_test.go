@@ -0,0 +1,150 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnmarshal(t *testing.T) {
+	var v struct {
+		Name     string  `mpd:"name"`
+		Count    int     `mpd:"count"`
+		Size     uint8   `mpd:"size"`
+		Ratio    float64 `mpd:"ratio"`
+		Enabled  bool    `mpd:"enabled"`
+		Missing  string  `mpd:"missing"`
+		Skipped  string  `mpd:"-"`
+		Untagged string
+	}
+
+	a := Args{
+		{"name", "first"},
+		{"name", "second"},
+		{"count", "-3"},
+		{"size", "200"},
+		{"ratio", "0.5"},
+		{"enabled", "1"},
+		{"-", "x"},
+		{"Untagged", "x"},
+	}
+
+	if err := Unmarshal(a, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Name != "first" || v.Count != -3 || v.Size != 200 || v.Ratio != 0.5 ||
+		!v.Enabled || v.Missing != "" || v.Skipped != "" || v.Untagged != "" {
+		t.Fatalf("unexpected result: %+v", v)
+	}
+
+	if err := Unmarshal(Args{{"size", "300"}}, &v); err == nil {
+		t.Fatal("expected an error for an out of range value")
+	}
+
+	if err := Unmarshal(a, v); err == nil {
+		t.Fatal("expected an error for a non-pointer")
+	}
+}
+
+func TestUnmarshalTypes(t *testing.T) {
+	var v struct {
+		Elapsed  time.Duration       `mpd:"elapsed"`
+		Modified time.Time           `mpd:"Last-Modified"`
+		Updated  time.Time           `mpd:"db_update"`
+		Artists  []string            `mpd:"Artist"`
+		Tracks   []int               `mpd:"Track"`
+		Next     *int                `mpd:"nextsong"`
+		Missing  *int                `mpd:"missing"`
+		State    PlayState           `mpd:"state"`
+		Rest     map[string][]string `mpd:"*"`
+	}
+
+	a := Args{
+		{"elapsed", "12.5"},
+		{"Last-Modified", "2021-03-04T05:06:07Z"},
+		{"db_update", "1600000000"},
+		{"Artist", "Tool"},
+		{"Artist", "Björk"},
+		{"Track", "1"},
+		{"Track", "2"},
+		{"nextsong", "4"},
+		{"state", "pause"},
+		{"Comment", "one"},
+		{"Comment", "two"},
+		{"bitrate", "320"},
+	}
+
+	if err := Unmarshal(a, &v); err != nil {
+		t.Fatal(err)
+	}
+
+	if v.Elapsed != 12500*time.Millisecond {
+		t.Errorf("got elapsed %v", v.Elapsed)
+	}
+
+	if !v.Modified.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)) {
+		t.Errorf("got modified %v", v.Modified)
+	}
+
+	if v.Updated.Unix() != 1600000000 {
+		t.Errorf("got updated %v", v.Updated)
+	}
+
+	if !reflect.DeepEqual(v.Artists, []string{"Tool", "Björk"}) || !reflect.DeepEqual(v.Tracks, []int{1, 2}) {
+		t.Errorf("got artists %q and tracks %v", v.Artists, v.Tracks)
+	}
+
+	if v.Next == nil || *v.Next != 4 || v.Missing != nil {
+		t.Errorf("got next %v and missing %v", v.Next, v.Missing)
+	}
+
+	if v.State != Paused {
+		t.Errorf("got state %v", v.State)
+	}
+
+	want := map[string][]string{"Comment": {"one", "two"}, "bitrate": {"320"}}
+	if !reflect.DeepEqual(v.Rest, want) {
+		t.Errorf("got rest %v, want %v", v.Rest, want)
+	}
+}
+
+func TestCommandSplit(t *testing.T) {
+	srv := newTestServer(t)
+	srv.AddOutput("Speakers", "alsa", true)
+	srv.AddOutput("Stream", "httpd", false)
+
+	c := dialTestServer(t, srv)
+
+	list, err := c.CommandSplit([]string{"outputid"}, "outputs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type output struct {
+		Id      int    `mpd:"outputid"`
+		Name    string `mpd:"outputname"`
+		Plugin  string `mpd:"plugin"`
+		Enabled bool   `mpd:"outputenabled"`
+	}
+
+	outputs, err := UnmarshalList[output](list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outputs) != 2 || outputs[1] != (output{1, "Stream", "httpd", false}) {
+		t.Fatalf("unexpected outputs: %+v", outputs)
+	}
+
+	a, err := c.Command("status")
+	if err != nil {
+		t.Fatal(err)
+	} else if !a.Has("state") {
+		t.Fatalf("unexpected status: %v", a)
+	}
+}
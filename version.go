@@ -0,0 +1,130 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a protocol version: major, minor and patch number. Versions
+// compare with == and order with Compare.
+type Version [3]int
+
+// Protocol versions which introduced features used by this package.
+var (
+	versionFilter          = Version{0, 21, 0} // Filter expressions.
+	versionQueryOptions    = Version{0, 21, 0} // sort and window in find and search.
+	versionAlbumArt        = Version{0, 21, 0} // albumart.
+	versionSingleOneshot   = Version{0, 21, 0} // single oneshot.
+	versionOutputSet       = Version{0, 21, 0} // outputset.
+	versionPartition       = Version{0, 21, 0} // partition, listpartitions, newpartition and moveoutput.
+	versionReadPicture     = Version{0, 22, 0} // readpicture.
+	versionDelPartition    = Version{0, 22, 0} // delpartition.
+	versionBinaryLimit     = Version{0, 22, 4} // binarylimit.
+	versionAddPosition     = Version{0, 23, 0} // Position argument of findadd and searchadd.
+	versionStartsWith      = Version{0, 24, 0} // starts_with in filters.
+	versionAddedSince      = Version{0, 24, 0} // added-since in filters.
+	versionPrioFilter      = Version{0, 24, 0} // prio in filters.
+	versionPlaylistAddPos  = Version{0, 24, 0} // Position argument of searchaddpl.
+	versionPlaylistOptions = Version{0, 24, 0} // sort and window in playlistfind and playlistsearch.
+	versionStickerInc      = Version{0, 24, 0} // sticker inc and dec.
+	versionStickerFind     = Version{0, 24, 0} // Integer operators, contains, starts_with and options in sticker find.
+	versionStickerPlaylist = Version{0, 24, 0} // Stickers on stored playlists.
+)
+
+// ParseVersion parses a version such as "0.23.5". The "MPD " prefix sent in
+// the greeting of the server is accepted as well. Missing minor and patch
+// numbers are taken to be zero.
+func ParseVersion(s string) (v Version, err error) {
+	s = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), "MPD "))
+
+	parts := strings.Split(s, ".")
+	if len(parts) > len(v) || len(parts[0]) == 0 {
+		return v, errors.New("Invalid version: " + s)
+	}
+
+	for i, p := range parts {
+		if v[i], err = strconv.Atoi(p); err != nil || v[i] < 0 {
+			return Version{}, errors.New("Invalid version: " + s)
+		}
+	}
+
+	return
+}
+
+// String returns the version in its dotted form.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v[0], v[1], v[2])
+}
+
+// Compare returns -1, 0 or +1 depending on whether v is older than, equal
+// to or newer than o.
+func (v Version) Compare(o Version) int {
+	for i := range v {
+		switch {
+		case v[i] < o[i]:
+			return -1
+		case v[i] > o[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is o or newer.
+func (v Version) AtLeast(o Version) bool {
+	return v.Compare(o) >= 0
+}
+
+// UnsupportedError is returned when the protocol version of the server is
+// too old for a command, or for this package as a whole. It matches
+// errors.ErrUnsupported.
+type UnsupportedError struct {
+	Command string  // Empty if the server was rejected as a whole.
+	Need    Version // Oldest version supporting the command.
+	Have    Version // Version of the server.
+}
+
+func (e *UnsupportedError) Error() string {
+	if len(e.Command) == 0 {
+		return fmt.Sprintf("Server protocol version %s is older than the required %s.", e.Have, e.Need)
+	}
+	return fmt.Sprintf("Command %q requires protocol version %s, but the server has %s.", e.Command, e.Need, e.Have)
+}
+
+// Is reports whether target is errors.ErrUnsupported.
+func (e *UnsupportedError) Is(target error) bool {
+	return target == errors.ErrUnsupported
+}
+
+// require returns an *UnsupportedError if the server is older than need.
+func (c *Client) require(cmd string, need Version) error {
+	have := c.ServerVersion()
+	if have.AtLeast(need) {
+		return nil
+	}
+	return &UnsupportedError{Command: cmd, Need: need, Have: have}
+}
+
+// ServerVersion returns the protocol version of the server the client is
+// connected to. It may change after Reconnect.
+func (c *Client) ServerVersion() Version {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.version
+}
+
+// ProtocolVersion returns the version the server sent in its greeting, eg:
+// "MPD 0.23.5". It may change after Reconnect.
+func (c *Client) ProtocolVersion() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.protocolVersion
+}
@@ -0,0 +1,66 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	for _, tt := range []struct {
+		in   string
+		want Version
+		ok   bool
+	}{
+		{"0.23.5", Version{0, 23, 5}, true},
+		{"MPD 0.21.11", Version{0, 21, 11}, true},
+		{"0.22", Version{0, 22, 0}, true},
+		{"1", Version{1, 0, 0}, true},
+		{"", Version{}, false},
+		{"0.x.1", Version{}, false},
+		{"0.1.2.3", Version{}, false},
+		{"0.-1", Version{}, false},
+	} {
+		v, err := ParseVersion(tt.in)
+		if (err == nil) != tt.ok || v != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v", tt.in, v, err, tt.want)
+		}
+	}
+
+	if !(Version{0, 21, 0}).AtLeast(Version{0, 20, 22}) {
+		t.Error("0.21.0 is not at least 0.20.22")
+	}
+
+	if (Version{0, 9, 0}).Compare(Version{0, 10, 0}) != -1 {
+		t.Error("0.9.0 is not older than 0.10.0")
+	}
+}
+
+func TestUnsupportedVersion(t *testing.T) {
+	srv := newTestServer(t)
+	srv.SetVersion("0.20.0")
+
+	c := dialTestServer(t, srv)
+	if v := c.ServerVersion(); v != (Version{0, 20, 0}) {
+		t.Fatalf("got version %v, want 0.20.0", v)
+	}
+
+	_, _, err := c.AlbumArt("Tool/Lateralus/01 The Grudge.flac")
+	if !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("AlbumArt: got %v, want unsupported error", err)
+	}
+
+	var ue *UnsupportedError
+	if !errors.As(err, &ue) || ue.Command != "albumart" || ue.Need != versionAlbumArt {
+		t.Fatalf("AlbumArt: unexpected error %#v", err)
+	}
+
+	d := Dialer{MinVersion: Version{0, 21, 0}}
+	if _, err = d.DialContext(context.Background(), "tcp", srv.Addr(), ""); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("DialContext: got %v, want unsupported error", err)
+	}
+}
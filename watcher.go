@@ -0,0 +1,190 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"context"
+	"time"
+)
+
+// Delays between attempts to reconnect a Watcher or Subscriber. The delay
+// doubles after every failed attempt, up to the maximum.
+const (
+	minBackoff = 100 * time.Millisecond
+	maxBackoff = 30 * time.Second
+)
+
+// backoff returns the delay before the next attempt to reconnect, after the
+// given number of consecutive failures.
+func backoff(failures int) time.Duration {
+	delay := minBackoff << uint(failures)
+	if delay > maxBackoff || delay <= 0 {
+		delay = maxBackoff
+	}
+	return delay
+}
+
+// waitBackoff waits for the delay returned by backoff. It reports false if
+// ctx ended first.
+func waitBackoff(ctx context.Context, failures int) bool {
+	timer := time.NewTimer(backoff(failures))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Watcher waits for changes on a dedicated connection to the server and
+// delivers the changed subsystems on its Event channel.
+//
+// When the connection is lost, the watcher reports the error on its Error
+// channel and reconnects. Changes made while it was disconnected are
+// unknown, so after reconnecting every watched subsystem is reported as
+// changed.
+//
+// Both channels must be drained until they are closed by Close.
+type Watcher struct {
+	Event <-chan SubSystem
+	Error <-chan error
+
+	event      chan SubSystem
+	error      chan error
+	ctx        context.Context
+	cancel     context.CancelFunc
+	exited     chan struct{}
+	network    string
+	address    string
+	password   string
+	subsystems []SubSystem
+}
+
+// NewWatcher connects to the MPD server at the given address and starts
+// watching it. See DialNetwork for the meaning of the arguments.
+//
+//     subsystems: The subsystems to watch. If none are given, all
+//                 subsystems are watched.
+func NewWatcher(network, address, password string, subsystems ...SubSystem) (w *Watcher, err error) {
+	w = new(Watcher)
+	w.event = make(chan SubSystem)
+	w.error = make(chan error)
+	w.Event = w.event
+	w.Error = w.error
+	w.exited = make(chan struct{})
+	w.network = network
+	w.address = address
+	w.password = password
+	w.subsystems = subsystems
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+
+	var c *Client
+	if c, err = DialContext(w.ctx, network, address, password); err != nil {
+		w.cancel()
+		return nil, err
+	}
+
+	go w.run(c)
+	return
+}
+
+// Close stops the watcher. The pending idle is ended with 'noidle' and the
+// connection is closed. Both channels are closed before Close returns.
+func (w *Watcher) Close() error {
+	w.cancel()
+	<-w.exited
+	return nil
+}
+
+func (w *Watcher) run(c *Client) {
+	defer close(w.exited)
+	defer close(w.error)
+	defer close(w.event)
+
+	var failures int
+	var list []SubSystem
+	var err error
+
+	for {
+		if c == nil {
+			if c = w.reconnect(&failures); c == nil {
+				return
+			}
+
+			if list = w.subsystems; len(list) == 0 {
+				for i := range subSystemNames {
+					list = append(list, SubSystem(i))
+				}
+			}
+		} else {
+			list, err = c.WithContext(w.ctx).IdleSubSystems(w.subsystems...)
+
+			if w.ctx.Err() != nil {
+				c.Close()
+				return
+			}
+
+			if err != nil {
+				c.Close()
+				c = nil
+
+				failures++
+				if !w.sendError(err) {
+					return
+				}
+				continue
+			}
+
+			failures = 0
+		}
+
+		for _, s := range list {
+			select {
+			case w.event <- s:
+			case <-w.ctx.Done():
+				c.Close()
+				return
+			}
+		}
+	}
+}
+
+// reconnect dials the server until it succeeds or the watcher is closed.
+// failures holds the number of consecutive failures so far and determines
+// the delay before the next attempt.
+func (w *Watcher) reconnect(failures *int) *Client {
+	for {
+		if !waitBackoff(w.ctx, *failures) {
+			return nil
+		}
+
+		c, err := DialContext(w.ctx, w.network, w.address, w.password)
+		if err == nil {
+			return c
+		}
+
+		if w.ctx.Err() != nil {
+			return nil
+		}
+
+		*failures++
+		if !w.sendError(err) {
+			return nil
+		}
+	}
+}
+
+// sendError delivers err on the Error channel. It reports false if the
+// watcher was closed instead.
+func (w *Watcher) sendError(err error) bool {
+	select {
+	case w.error <- err:
+		return true
+	case <-w.ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+// This work is subject to the CC0 1.0 Universal (CC0 1.0) Public Domain
+// Dedication license. Its contents can be found at:
+// http://creativecommons.org/publicdomain/zero/1.0/
+
+package mpd
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatcher(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	// The first connection reports a single change and then drops, as if
+	// the server restarted. The second waits for noidle.
+	go func() {
+		for i := 0; ; i++ {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func(i int) {
+				defer conn.Close()
+				io.WriteString(conn, "OK MPD 0.23.5\n")
+
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+
+					switch strings.TrimSpace(line) {
+					case `idle "player" "mixer"`:
+						if i == 0 {
+							io.WriteString(conn, "changed: mixer\nOK\n")
+							return
+						}
+					case "noidle":
+						io.WriteString(conn, "OK\n")
+					}
+				}
+			}(i)
+		}
+	}()
+
+	w, err := NewWatcher("tcp", ln.Addr().String(), "", PlayerSystem, MixerSystem)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SubSystem{MixerSystem, PlayerSystem, MixerSystem}
+	var errors int
+
+	for len(want) > 0 {
+		select {
+		case s := <-w.Event:
+			if s != want[0] {
+				t.Fatalf("got event %v, want %v", s, want[0])
+			}
+			want = want[1:]
+		case <-w.Error:
+			errors++
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timed out waiting for %v", want)
+		}
+	}
+
+	if errors == 0 {
+		t.Error("dropped connection was not reported")
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := <-w.Event; ok {
+		t.Error("Event channel still open after Close")
+	}
+}
+
+func TestWatcherOptions(t *testing.T) {
+	srv := newTestServer(t)
+
+	w, err := NewWatcher("tcp", srv.Addr(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if err = dialTestServer(t, srv).Random(true); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case s := <-w.Event:
+		if s != OptionsSystem {
+			t.Fatalf("got event %v, want %v", s, OptionsSystem)
+		}
+	case err = <-w.Error:
+		t.Fatal(err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("options change was not reported")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for _, tt := range []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, minBackoff},
+		{1, 2 * minBackoff},
+		{3, 8 * minBackoff},
+		{20, maxBackoff},
+		{100, maxBackoff},
+	} {
+		if got := backoff(tt.failures); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}